@@ -15,11 +15,26 @@ package alertparser
 
 import (
 	"fmt"
+	"hash/fnv"
 	"log/slog"
 	"strings"
 
 	"github.com/maxwo/snmp_notifier/commons"
 	"github.com/maxwo/snmp_notifier/types"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// PerOIDGrouping groups the alerts sharing a trap OID into one trap
+	PerOIDGrouping = "per-oid"
+	// PerAlertGrouping sends one trap per alert
+	PerAlertGrouping = "per-alert"
+
+	// KeyAlertID identifies the traps with their grouping key
+	KeyAlertID = "key"
+	// HashAlertID identifies the traps with a hash of their grouping key
+	HashAlertID = "hash"
 )
 
 // AlertParser parses alerts from the Prometheus Alert Manager
@@ -31,6 +46,7 @@ type AlertParser struct {
 // Configuration stores configuration of an AlertParser
 type Configuration struct {
 	Severities                []string
+	SeverityLevels            map[string]int
 	SeverityLabel             string
 	DefaultSeverity           string
 	TrapDefaultOID            string
@@ -39,6 +55,9 @@ type Configuration struct {
 	TrapResolutionOIDLabel    *string
 	TrapDefaultObjectsBaseOID string
 	TrapUserObjectsBaseOID    string
+	TrapGrouping              string
+	TrapAlertID               string
+	OIDResolver               *commons.OIDResolver
 }
 
 // New creates an AlertParser instance
@@ -62,22 +81,42 @@ func (alertParser AlertParser) Parse(alertsData types.AlertsData) (*types.AlertB
 		if err != nil {
 			return nil, err
 		}
-		alertParser.logger.Debug("add to a new group", "group", *alertIDForGrouping, "alert", alert)
 
 		key := strings.Join([]string{*alertIDForGrouping, "[", groupID, "]"}, "")
+		fingerprint := getFingerprint(alert)
+		if alertParser.configuration.TrapGrouping == PerAlertGrouping {
+			key = fingerprint
+		}
+		if alertParser.configuration.TrapAlertID == HashAlertID {
+			key = hashKey(key)
+		}
+		alertParser.logger.Debug("add to a group", "group", key, "alert", alert)
+
 		if _, found := alertGroups[key]; !found {
 			alertGroups[key] = &types.AlertGroup{
 				TrapOID:               *trapOID,
 				GroupID:               groupID,
+				GroupKey:              alertsData.GroupKey,
+				Receiver:              alertsData.Receiver,
 				GroupLabels:           alertsData.GroupLabels,
 				CommonLabels:          alertsData.CommonLabels,
 				CommonAnnotations:     alertsData.CommonAnnotations,
 				Severity:              alertParser.getLowestSeverity(),
+				SeverityLevel:         alertParser.getSeverityLevel(alertParser.getLowestSeverity()),
 				Alerts:                []types.Alert{},
 				DeclaredAlerts:        []types.Alert{},
 				DefaultObjectsBaseOID: alertParser.configuration.TrapDefaultObjectsBaseOID,
 				UserObjectsBaseOID:    alertParser.configuration.TrapUserObjectsBaseOID,
 			}
+			if alertParser.configuration.TrapGrouping == PerAlertGrouping {
+				// the group only holds this alert, so its labels are the common ones
+				alertGroups[key].Fingerprint = fingerprint
+				alertGroups[key].CommonLabels = alert.Labels
+				alertGroups[key].CommonAnnotations = alert.Annotations
+				// resolved alerts keep their own severity as well
+				alertGroups[key].Severity = alertParser.getAlertSeverity(alert)
+				alertGroups[key].SeverityLevel = alertParser.getSeverityLevel(alertGroups[key].Severity)
+			}
 		}
 		alertGroups[key].DeclaredAlerts = append(alertGroups[key].DeclaredAlerts, alert)
 		if alert.Status == "firing" {
@@ -92,11 +131,7 @@ func (alertParser AlertParser) Parse(alertsData types.AlertsData) (*types.AlertB
 }
 
 func (alertParser AlertParser) addAlertToGroup(alertGroup *types.AlertGroup, alert types.Alert) error {
-	var severity = alertParser.configuration.DefaultSeverity
-	if _, found := alert.Labels[alertParser.configuration.SeverityLabel]; found {
-		severity = alert.Labels[alertParser.configuration.SeverityLabel]
-	}
-
+	var severity = alertParser.getAlertSeverity(alert)
 	var currentGroupSeverityIndex = commons.IndexOf(alertGroup.Severity, alertParser.configuration.Severities)
 	var alertSeverityIndex = commons.IndexOf(severity, alertParser.configuration.Severities)
 	if alertSeverityIndex == -1 {
@@ -105,11 +140,20 @@ func (alertParser AlertParser) addAlertToGroup(alertGroup *types.AlertGroup, ale
 	// Update group severity
 	if alertSeverityIndex < currentGroupSeverityIndex {
 		alertGroup.Severity = severity
+		alertGroup.SeverityLevel = alertParser.getSeverityLevel(severity)
 	}
 	alertGroup.Alerts = append(alertGroup.Alerts, alert)
 	return nil
 }
 
+// getAlertSeverity returns the severity label of an alert, or the default severity when it has none
+func (alertParser AlertParser) getAlertSeverity(alert types.Alert) string {
+	if severity, found := alert.Labels[alertParser.configuration.SeverityLabel]; found {
+		return severity
+	}
+	return alertParser.configuration.DefaultSeverity
+}
+
 func (alertParser AlertParser) getAlertOID(alert types.Alert) (*string, error) {
 	if alert.Status == "firing" {
 		return alertParser.getFiringAlertOID(alert)
@@ -145,11 +189,12 @@ func (alertParser AlertParser) getFiringAlertOID(alert types.Alert) (*string, er
 		firingTrapOID = value
 	}
 
-	if !commons.IsOID(firingTrapOID) {
-		return nil, fmt.Errorf("invalid OID provided: \"%s\"", firingTrapOID)
+	oid, err := alertParser.configuration.OIDResolver.ResolveOID(firingTrapOID)
+	if err != nil {
+		return nil, err
 	}
 
-	return &firingTrapOID, nil
+	return &oid, nil
 }
 
 func (alertParser AlertParser) getResolvedAlertOID(alert types.Alert) (*string, error) {
@@ -169,17 +214,50 @@ func (alertParser AlertParser) getResolvedAlertOID(alert types.Alert) (*string,
 		}
 	}
 
-	if !commons.IsOID(resolvedTrapOID) {
-		return nil, fmt.Errorf("invalid OID provided: \"%s\"", resolvedTrapOID)
+	oid, err := alertParser.configuration.OIDResolver.ResolveOID(resolvedTrapOID)
+	if err != nil {
+		return nil, err
 	}
 
-	return &resolvedTrapOID, nil
+	return &oid, nil
 }
 
 func (alertParser AlertParser) getLowestSeverity() string {
 	return alertParser.configuration.Severities[len(alertParser.configuration.Severities)-1]
 }
 
+// getSeverityLevel returns the level of a severity from the explicit mapping if any,
+// or from its position in the severities, starting with 1 for the highest priority.
+// Unknown severities get the level of the lowest severity, so that no trap is sent with the level 0.
+func (alertParser AlertParser) getSeverityLevel(severity string) int {
+	if commons.IndexOf(severity, alertParser.configuration.Severities) == -1 {
+		severity = alertParser.getLowestSeverity()
+	}
+	if level, found := alertParser.configuration.SeverityLevels[severity]; found {
+		return level
+	}
+	return commons.IndexOf(severity, alertParser.configuration.Severities) + 1
+}
+
+// getFingerprint returns the fingerprint of an alert, computed from its labels when not provided by the Alertmanager
+func getFingerprint(alert types.Alert) string {
+	if alert.Fingerprint != "" {
+		return alert.Fingerprint
+	}
+	labelSet := model.LabelSet{}
+	for name, value := range alert.Labels {
+		labelSet[model.LabelName(name)] = model.LabelValue(value)
+	}
+	return labelSet.Fingerprint().String()
+}
+
+// hashKey returns a hash of a grouping key, formatted as an Alertmanager fingerprint
+func hashKey(key string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
 func generateGroupID(alertsData types.AlertsData) string {
 	var (
 		pairs []string
@@ -18,8 +18,10 @@ import (
 	"encoding/json"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/maxwo/snmp_notifier/commons"
 	"github.com/maxwo/snmp_notifier/types"
 
 	"github.com/go-test/deep"
@@ -207,6 +209,176 @@ func TestMixedAlertBuckets(t *testing.T) {
 	)
 }
 
+func TestMixedAlertBucketsPerAlert(t *testing.T) {
+	alerts := readAlertFile(t, "test_mixed_alerts.json")
+	buckets := readBucketsFile(t, "test_mixed_per_alert_bucket.json")
+
+	expectAlertBuckets(
+		t,
+		Configuration{
+			TrapDefaultOID:            "1.1",
+			TrapOIDLabel:              "oid",
+			DefaultSeverity:           "critical",
+			Severities:                []string{"critical", "warning", "info"},
+			SeverityLabel:             "severity",
+			TrapDefaultObjectsBaseOID: "4.4.4",
+			TrapUserObjectsBaseOID:    "4.4.5",
+			TrapGrouping:              PerAlertGrouping,
+		},
+		alerts,
+		buckets,
+	)
+}
+
+func TestPerAlertGroupingUsesAlertmanagerFingerprint(t *testing.T) {
+	alerts := readAlertFile(t, "test_unique_alert.json")
+	alerts.Alerts[0].Fingerprint = "a1b2c3d4e5f60718"
+
+	expectTrapOIDAndGroupIDFromAlertAndConfiguration(t,
+		Configuration{
+			TrapDefaultOID:            "1.1",
+			TrapOIDLabel:              "firing-oid",
+			DefaultSeverity:           "critical",
+			Severities:                []string{"critical", "warning", "info"},
+			SeverityLabel:             "severity",
+			TrapDefaultObjectsBaseOID: "4.4.4",
+			TrapUserObjectsBaseOID:    "4.4.5",
+			TrapGrouping:              PerAlertGrouping,
+		},
+		alerts,
+		"a1b2c3d4e5f60718",
+		"1.2.3")
+}
+
+func TestHashedAlertIDWithGroupKey(t *testing.T) {
+	alerts := readAlertFile(t, "test_unique_alert.json")
+	alerts.GroupKey = `{}:{environment="production", label="test"}`
+
+	buckets := getAlertBuckets(t,
+		Configuration{
+			TrapDefaultOID:            "1.1",
+			TrapOIDLabel:              "firing-oid",
+			TrapResolutionOIDLabel:    &resolutionOIDLabelForTest,
+			DefaultSeverity:           "critical",
+			Severities:                []string{"critical", "warning", "info"},
+			SeverityLabel:             "severity",
+			TrapDefaultObjectsBaseOID: "4.4.4",
+			TrapUserObjectsBaseOID:    "4.4.5",
+			TrapAlertID:               HashAlertID,
+		},
+		alerts)
+
+	// hash of 1.2.3-1.2.4[environment=production,label=test]
+	alertGroup, found := buckets.AlertGroups["a54591a70353d1cf"]
+	if !found {
+		t.Fatal("expected hashed alert ID not found", "buckets", buckets)
+	}
+	if alertGroup.GroupKey != alerts.GroupKey {
+		t.Error("unexpected group key", "groupKey", alertGroup.GroupKey)
+	}
+}
+
+func TestHashedAlertIDPerAlert(t *testing.T) {
+	alerts := readAlertFile(t, "test_unique_alert.json")
+	alerts.Alerts[0].Fingerprint = "a1b2c3d4e5f60718"
+
+	buckets := getAlertBuckets(t,
+		Configuration{
+			TrapDefaultOID:            "1.1",
+			TrapOIDLabel:              "firing-oid",
+			DefaultSeverity:           "critical",
+			Severities:                []string{"critical", "warning", "info"},
+			SeverityLabel:             "severity",
+			TrapDefaultObjectsBaseOID: "4.4.4",
+			TrapUserObjectsBaseOID:    "4.4.5",
+			TrapGrouping:              PerAlertGrouping,
+			TrapAlertID:               HashAlertID,
+		},
+		alerts)
+
+	alertGroup, found := buckets.AlertGroups[hashKey("a1b2c3d4e5f60718")]
+	if !found {
+		t.Fatal("expected hashed alert ID not found", "buckets", buckets)
+	}
+	if alertGroup.Fingerprint != "a1b2c3d4e5f60718" {
+		t.Error("the fingerprint should be the one of the alert", "fingerprint", alertGroup.Fingerprint)
+	}
+}
+
+func TestExplicitSeverityLevels(t *testing.T) {
+	alerts := readAlertFile(t, "test_mixed_alerts.json")
+
+	buckets := getAlertBuckets(t,
+		Configuration{
+			TrapDefaultOID:            "1.1",
+			TrapOIDLabel:              "oid",
+			DefaultSeverity:           "critical",
+			Severities:                []string{"critical", "warning", "info"},
+			SeverityLevels:            map[string]int{"critical": 5, "warning": 3, "info": 1},
+			SeverityLabel:             "severity",
+			TrapDefaultObjectsBaseOID: "4.4.4",
+			TrapUserObjectsBaseOID:    "4.4.5",
+		},
+		alerts)
+
+	for groupID, expectedLevel := range map[string]int{
+		"1.2.3.2.1[environment=production,label=test]": 5,
+		"1.2.3.1.1[environment=production,label=test]": 1,
+	} {
+		if level := buckets.AlertGroups[groupID].SeverityLevel; level != expectedLevel {
+			t.Error("unexpected severity level", "groupID", groupID, "level", level)
+		}
+	}
+}
+
+func TestUnknownSeverityLevel(t *testing.T) {
+	for _, severityLevels := range []map[string]int{nil, {"critical": 5, "warning": 3, "info": 2}} {
+		alertParser := New(Configuration{
+			Severities:     []string{"critical", "warning", "info"},
+			SeverityLevels: severityLevels,
+		}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+		// unknown severities get the level of the lowest severity
+		expected := alertParser.getSeverityLevel("info")
+		if level := alertParser.getSeverityLevel("debug"); level != expected || level == 0 {
+			t.Error("unexpected level of an unknown severity", "level", level, "expected", expected)
+		}
+	}
+}
+
+func TestSymbolicOIDLabels(t *testing.T) {
+	alerts := readAlertFile(t, "test_mixed_alerts.json")
+	alerts.Alerts[0].Labels["oid"] = "SNMP-NOTIFIER-MIB::snmpNotifierDefaultTrap"
+
+	resolver, err := commons.LoadMIBs("../mibs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configuration := Configuration{
+		TrapDefaultOID:            "1.1",
+		TrapOIDLabel:              "oid",
+		DefaultSeverity:           "critical",
+		Severities:                []string{"critical", "warning", "info"},
+		SeverityLabel:             "severity",
+		TrapDefaultObjectsBaseOID: "4.4.4",
+		TrapUserObjectsBaseOID:    "4.4.5",
+		OIDResolver:               resolver,
+	}
+	buckets := getAlertBuckets(t, configuration, alerts)
+
+	found := false
+	for groupID := range buckets.AlertGroups {
+		found = found || strings.HasPrefix(groupID, "1.3.6.1.4.1.98789.1[")
+	}
+	if !found {
+		t.Error("the symbolic OID label should be resolved", "groups", buckets.AlertGroups)
+	}
+
+	alerts.Alerts[0].Labels["oid"] = "SNMP-NOTIFIER-MIB::snmpNotifierUnknownTrap"
+	expectAlertParserError(t, configuration, alerts)
+}
+
 func TestAlertBucketsWithTrapResolutionDefaultOID(t *testing.T) {
 	alerts := readAlertFile(t, "test_resolved_alerts.json")
 	buckets := readBucketsFile(t, "test_resolved_default_resolved_oid_alerts.json")
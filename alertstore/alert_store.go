@@ -0,0 +1,291 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertstore
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/maxwo/snmp_notifier/telemetry"
+	"github.com/maxwo/snmp_notifier/trapsender"
+	"github.com/maxwo/snmp_notifier/types"
+)
+
+// interval between two checks of the alert groups to re-notify
+var checkInterval = time.Second
+
+// AlertStore keeps the firing alert groups, and re-sends their traps periodically until they are resolved
+type AlertStore struct {
+	logger        *slog.Logger
+	configuration Configuration
+	trapSender    trapsender.TrapSender
+	groups        map[string]*storedGroup
+	// version changes with the alert groups and the trap sender, so that their views may be cached
+	version     uint64
+	mutex       sync.Mutex
+	stopChannel chan struct{}
+	stopOnce    sync.Once
+}
+
+// Configuration describes the storage and the re-notification of the firing alert groups
+type Configuration struct {
+	// RenotifyIntervals gives the interval between two notifications of the alert groups of a severity.
+	// The groups of the other severities are not re-notified.
+	RenotifyIntervals map[string]time.Duration
+	// StateFile persists the firing alert groups across restarts, when not empty
+	StateFile string
+	// KeepAlerts stores the firing alert groups even when they are neither re-notified nor persisted, e.g. to expose
+	// them with the SNMP agent
+	KeepAlerts bool
+	// MaxAge forgets the alert groups that Alertmanager did not notify for that long, e.g. when their resolution is
+	// not sent. Unlimited when 0.
+	MaxAge time.Duration
+}
+
+// storedGroup is a firing alert group, with the last time it was received from Alertmanager, and the last time its
+// traps were sent
+type storedGroup struct {
+	AlertGroup   types.AlertGroup `json:"alertGroup"`
+	LastRecorded time.Time        `json:"lastRecorded"`
+	LastNotified time.Time        `json:"lastNotified"`
+}
+
+// New creates an AlertStore, with the alert groups persisted in the state file
+func New(configuration Configuration, trapSender trapsender.TrapSender, logger *slog.Logger) *AlertStore {
+	store := &AlertStore{
+		logger:        logger,
+		configuration: configuration,
+		trapSender:    trapSender,
+		groups:        map[string]*storedGroup{},
+		stopChannel:   make(chan struct{}),
+	}
+	if configuration.StateFile != "" {
+		if err := store.load(); err != nil {
+			logger.Error("error while loading the alert state file, starting without alerts", "file", configuration.StateFile, "err", err.Error())
+		}
+	}
+	telemetry.ActiveAlertGroups.Set(float64(len(store.groups)))
+	return store
+}
+
+// IsEnabled tells whether the alert groups are stored, to be re-notified, persisted or exposed
+func (store *AlertStore) IsEnabled() bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return len(store.configuration.RenotifyIntervals) > 0 || store.configuration.StateFile != "" || store.configuration.KeepAlerts
+}
+
+// Groups returns a copy of the firing alert groups, indexed by their ID, with the trap sender generating their traps
+// and the version of the store
+func (store *AlertStore) Groups() (map[string]types.AlertGroup, trapsender.TrapSender, uint64) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	groups := make(map[string]types.AlertGroup, len(store.groups))
+	for id, group := range store.groups {
+		groups[id] = group.AlertGroup
+	}
+	return groups, store.trapSender, store.version
+}
+
+// Version returns the version of the store, which changes when the alert groups or the trap sender are replaced
+func (store *AlertStore) Version() uint64 {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.version
+}
+
+// Update replaces the configuration and the trap sender, keeping the alert groups
+func (store *AlertStore) Update(configuration Configuration, trapSender trapsender.TrapSender) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.configuration = configuration
+	store.trapSender = trapSender
+	store.version++
+}
+
+// Record stores the firing alert groups of a notification that was sent, and forgets the resolved ones. The groups
+// of an Alertmanager notification hold all of its alerts, so they replace the groups of the previous notification
+// sharing its group key.
+func (store *AlertStore) Record(alertBucket types.AlertBucket) {
+	if !store.IsEnabled() {
+		return
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	groupKeys := map[string]bool{}
+	for _, alertGroup := range alertBucket.AlertGroups {
+		if alertGroup.GroupKey != "" {
+			groupKeys[alertGroup.GroupKey] = true
+		}
+	}
+	for id, group := range store.groups {
+		if groupKeys[group.AlertGroup.GroupKey] {
+			delete(store.groups, id)
+		}
+	}
+
+	now := time.Now()
+	for id, alertGroup := range alertBucket.AlertGroups {
+		if len(alertGroup.Alerts) == 0 {
+			delete(store.groups, id)
+			continue
+		}
+		store.groups[id] = &storedGroup{AlertGroup: *alertGroup, LastRecorded: now, LastNotified: now}
+	}
+	store.version++
+	store.groupsChanged()
+}
+
+// Start re-notifies the alert groups in the background, until the store is stopped
+func (store *AlertStore) Start() {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-store.stopChannel:
+				return
+			case <-ticker.C:
+				store.renotify(time.Now())
+			}
+		}
+	}()
+}
+
+// Stop stops the re-notification of the alert groups
+func (store *AlertStore) Stop() {
+	store.stopOnce.Do(func() {
+		close(store.stopChannel)
+	})
+}
+
+// renotify forgets the expired alert groups, and re-sends the traps of the alert groups whose interval elapsed since
+// their last notification
+func (store *AlertStore) renotify(now time.Time) {
+	store.mutex.Lock()
+	store.expire(now)
+	dueGroups := types.AlertBucket{AlertGroups: map[string]*types.AlertGroup{}}
+	for id, group := range store.groups {
+		interval, found := store.configuration.RenotifyIntervals[group.AlertGroup.Severity]
+		if found && interval > 0 && now.Sub(group.LastNotified) >= interval {
+			alertGroup := group.AlertGroup
+			dueGroups.AlertGroups[id] = &alertGroup
+		}
+	}
+	trapSender := store.trapSender
+	trapSender.Acquire()
+	store.mutex.Unlock()
+	defer trapSender.Release()
+
+	if len(dueGroups.AlertGroups) == 0 {
+		return
+	}
+
+	store.logger.Info("re-notifying firing alert groups", "count", len(dueGroups.AlertGroups))
+	if err := trapSender.SendAlertTraps(dueGroups); err != nil {
+		// the failed traps are retried by the delivery queues and the spool, re-sending them at the next check would
+		// flood the destinations that received them
+		store.logger.Error("error while re-notifying firing alert groups", "err", err.Error())
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for id, dueGroup := range dueGroups.AlertGroups {
+		// the group may have been replaced by a notification in the meantime
+		if group, found := store.groups[id]; found && group.AlertGroup.GroupKey == dueGroup.GroupKey && group.LastNotified.Before(now) {
+			group.LastNotified = now
+		}
+	}
+	store.groupsChanged()
+}
+
+// expire forgets the alert groups that Alertmanager did not notify for longer than the maximum age, with the mutex held
+func (store *AlertStore) expire(now time.Time) {
+	if store.configuration.MaxAge <= 0 {
+		return
+	}
+	expired := 0
+	for id, group := range store.groups {
+		if now.Sub(group.LastRecorded) >= store.configuration.MaxAge {
+			delete(store.groups, id)
+			expired++
+		}
+	}
+	if expired > 0 {
+		store.logger.Warn("forgetting firing alert groups not notified by Alertmanager", "count", expired, "max_age", store.configuration.MaxAge)
+		store.version++
+		store.groupsChanged()
+	}
+}
+
+// groupsChanged updates the metrics and the state file, with the mutex held
+func (store *AlertStore) groupsChanged() {
+	telemetry.ActiveAlertGroups.Set(float64(len(store.groups)))
+	if store.configuration.StateFile == "" {
+		return
+	}
+	if err := store.save(); err != nil {
+		store.logger.Error("error while saving the alert state file", "file", store.configuration.StateFile, "err", err.Error())
+	}
+}
+
+func (store *AlertStore) load() error {
+	content, err := os.ReadFile(store.configuration.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	groups := map[string]*storedGroup{}
+	if err := json.Unmarshal(content, &groups); err != nil {
+		return err
+	}
+	for _, group := range groups {
+		// state files written before the maximum age was introduced
+		if group.LastRecorded.IsZero() {
+			group.LastRecorded = group.LastNotified
+		}
+	}
+	store.groups = groups
+	return nil
+}
+
+// save writes the state file atomically, so that it is never read half-written
+func (store *AlertStore) save() error {
+	content, err := json.Marshal(store.groups)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(store.configuration.StateFile), filepath.Base(store.configuration.StateFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), store.configuration.StateFile)
+}
@@ -0,0 +1,244 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertstore
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/maxwo/snmp_notifier/telemetry"
+	"github.com/maxwo/snmp_notifier/trapsender"
+	"github.com/maxwo/snmp_notifier/types"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// the group of test_mixed_bucket.json with firing alerts, the other one being resolved
+const criticalGroupID = "1.2.3.2.1[environment=production,label=test]"
+
+func TestRenotifyFiringAlertGroups(t *testing.T) {
+	dryRunFile := filepath.Join(t.TempDir(), "traps.jsonl")
+	store := New(Configuration{RenotifyIntervals: map[string]time.Duration{"critical": time.Minute}}, newTrapSender(dryRunFile), newLogger())
+
+	store.Record(readBucketFile(t))
+	if _, found := store.groups[criticalGroupID]; !found || len(store.groups) != 1 {
+		t.Fatal("only the firing alert group should be stored, got", store.groups)
+	}
+	if groups := testutil.ToFloat64(telemetry.ActiveAlertGroups); groups != 1 {
+		t.Error("1 active alert group expected, got", groups)
+	}
+
+	now := time.Now()
+	store.renotify(now.Add(30 * time.Second))
+	expectDryRunTraps(t, dryRunFile, 0)
+
+	store.renotify(now.Add(time.Minute))
+	expectDryRunTraps(t, dryRunFile, 1)
+
+	// the interval restarts from the last notification
+	store.renotify(now.Add(90 * time.Second))
+	expectDryRunTraps(t, dryRunFile, 1)
+
+	store.renotify(now.Add(2 * time.Minute))
+	expectDryRunTraps(t, dryRunFile, 2)
+}
+
+func TestResolvedAlertGroupsAreForgotten(t *testing.T) {
+	store := New(Configuration{RenotifyIntervals: map[string]time.Duration{"critical": time.Minute}}, newTrapSender(""), newLogger())
+	store.Record(readBucketFile(t))
+
+	resolvedBucket := readBucketFile(t)
+	resolvedBucket.AlertGroups[criticalGroupID].Alerts = nil
+	store.Record(resolvedBucket)
+	if len(store.groups) != 0 {
+		t.Error("the resolved alert group should be forgotten, got", store.groups)
+	}
+	if groups := testutil.ToFloat64(telemetry.ActiveAlertGroups); groups != 0 {
+		t.Error("no active alert group expected, got", groups)
+	}
+}
+
+func TestAlertGroupsReplacedByTheirNotification(t *testing.T) {
+	store := New(Configuration{RenotifyIntervals: map[string]time.Duration{"critical": time.Minute}}, newTrapSender(""), newLogger())
+	store.Record(readBucketFile(t))
+
+	// the alert group of the same notification may get another ID, such as another trap OID
+	movedBucket := readBucketFile(t)
+	movedBucket.AlertGroups["1.2.3.9.1[environment=production,label=test]"] = movedBucket.AlertGroups[criticalGroupID]
+	delete(movedBucket.AlertGroups, criticalGroupID)
+	store.Record(movedBucket)
+	if _, found := store.groups["1.2.3.9.1[environment=production,label=test]"]; !found || len(store.groups) != 1 {
+		t.Error("the alert groups of the notification should be replaced, got", store.groups)
+	}
+}
+
+func TestDisabledAlertStore(t *testing.T) {
+	store := New(Configuration{}, newTrapSender(""), newLogger())
+	store.Record(readBucketFile(t))
+	if len(store.groups) != 0 {
+		t.Error("no alert group should be stored when disabled, got", store.groups)
+	}
+}
+
+func TestAlertStoreStateFile(t *testing.T) {
+	dryRunFile := filepath.Join(t.TempDir(), "traps.jsonl")
+	configuration := Configuration{
+		RenotifyIntervals: map[string]time.Duration{"critical": time.Minute},
+		StateFile:         filepath.Join(t.TempDir(), "alerts.json"),
+	}
+	New(configuration, newTrapSender(dryRunFile), newLogger()).Record(readBucketFile(t))
+
+	store := New(configuration, newTrapSender(dryRunFile), newLogger())
+	if _, found := store.groups[criticalGroupID]; !found || len(store.groups) != 1 {
+		t.Fatal("the firing alert group should be loaded from the state file, got", store.groups)
+	}
+	store.renotify(time.Now().Add(time.Minute))
+	expectDryRunTraps(t, dryRunFile, 1)
+
+	os.WriteFile(configuration.StateFile, []byte("not JSON"), 0o644)
+	if store := New(configuration, newTrapSender(dryRunFile), newLogger()); len(store.groups) != 0 {
+		t.Error("an invalid state file should be ignored, got", store.groups)
+	}
+}
+
+func TestFailedRenotificationIsNotRepeated(t *testing.T) {
+	connection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error while opening connection:", err)
+	}
+	address := connection.LocalAddr().String()
+	connection.Close()
+
+	trapSender := trapsender.New(trapsender.Configuration{
+		SNMPDestinations: []trapsender.Destination{
+			{Name: "unreachable", Address: address, Retries: 0, Version: "V2c", Timeout: 100 * time.Millisecond, Inform: true, Community: "public"},
+		},
+		DescriptionTemplate: *template.Must(template.New("description").Parse(`{{ len .Alerts }} alerts are firing`)),
+		UserObjects:         make([]trapsender.UserObject, 0),
+	}, newLogger())
+	store := New(Configuration{RenotifyIntervals: map[string]time.Duration{"critical": time.Minute}}, trapSender, newLogger())
+	store.Record(readBucketFile(t))
+
+	failures := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure"))
+	now := time.Now()
+	store.renotify(now.Add(time.Minute))
+	if count := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure")) - failures; count != 1 {
+		t.Fatal("1 failed trap expected, got", count)
+	}
+
+	// the interval restarts from the failed notification
+	store.renotify(now.Add(time.Minute + checkInterval))
+	if count := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure")) - failures; count != 1 {
+		t.Error("the failed notification should not be repeated at the next check, got failures:", count)
+	}
+}
+
+func TestExpiredAlertGroupsAreForgotten(t *testing.T) {
+	dryRunFile := filepath.Join(t.TempDir(), "traps.jsonl")
+	store := New(Configuration{RenotifyIntervals: map[string]time.Duration{"critical": time.Minute}, MaxAge: time.Hour}, newTrapSender(dryRunFile), newLogger())
+	store.Record(readBucketFile(t))
+	version := store.Version()
+
+	now := time.Now()
+	store.renotify(now.Add(59 * time.Minute))
+	expectDryRunTraps(t, dryRunFile, 1)
+
+	// the resolution was never received, the group is not re-notified forever
+	store.renotify(now.Add(time.Hour))
+	expectDryRunTraps(t, dryRunFile, 1)
+	if len(store.groups) != 0 || store.Version() == version {
+		t.Error("the expired alert group should be forgotten, got", store.groups)
+	}
+}
+
+func TestAlertStoreVersion(t *testing.T) {
+	trapSender := newTrapSender("")
+	store := New(Configuration{KeepAlerts: true}, trapSender, newLogger())
+	version := store.Version()
+
+	store.Record(readBucketFile(t))
+	if _, _, recorded := store.Groups(); recorded == version || store.Version() != recorded {
+		t.Error("the version should change when alert groups are recorded")
+	}
+
+	version = store.Version()
+	store.Update(Configuration{KeepAlerts: true}, trapSender)
+	if store.Version() == version {
+		t.Error("the version should change when the trap sender is replaced")
+	}
+}
+
+func TestStartAndStop(t *testing.T) {
+	defer func(interval time.Duration) { checkInterval = interval }(checkInterval)
+	checkInterval = 10 * time.Millisecond
+
+	dryRunFile := filepath.Join(t.TempDir(), "traps.jsonl")
+	store := New(Configuration{RenotifyIntervals: map[string]time.Duration{"critical": 10 * time.Millisecond}}, newTrapSender(dryRunFile), newLogger())
+	store.Record(readBucketFile(t))
+	store.Start()
+	time.Sleep(100 * time.Millisecond)
+	store.Stop()
+	store.Stop()
+
+	content, err := os.ReadFile(dryRunFile)
+	if err != nil || strings.Count(string(content), "\n") == 0 {
+		t.Error("the firing alert group should be re-notified", err)
+	}
+}
+
+func newTrapSender(dryRunFile string) trapsender.TrapSender {
+	return trapsender.New(trapsender.Configuration{
+		SNMPDestinations: []trapsender.Destination{
+			{Name: "dry-run", Address: "127.0.0.1:65162", Retries: 1, Version: "V2c", Timeout: 5 * time.Second, Community: "public"},
+		},
+		DryRun:              true,
+		DryRunFile:          dryRunFile,
+		DescriptionTemplate: *template.Must(template.New("description").Parse(`{{ len .Alerts }} alerts are firing`)),
+		UserObjects:         make([]trapsender.UserObject, 0),
+	}, newLogger())
+}
+
+func newLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, nil))
+}
+
+func readBucketFile(t *testing.T) types.AlertBucket {
+	content, err := os.ReadFile("../trapsender/test_mixed_bucket.json")
+	if err != nil {
+		t.Fatal("Error while reading bucket file:", err)
+	}
+	bucket := types.AlertBucket{}
+	if err := json.Unmarshal(content, &bucket); err != nil {
+		t.Fatal("Error while parsing bucket file:", err)
+	}
+	return bucket
+}
+
+func expectDryRunTraps(t *testing.T, dryRunFile string, count int) {
+	t.Helper()
+	content, err := os.ReadFile(dryRunFile)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal("Error while reading dry run file:", err)
+	}
+	if traps := strings.Count(string(content), "\n"); traps != count {
+		t.Errorf("%d traps expected, got %d", count, traps)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"text/template"
 
@@ -287,3 +288,173 @@ func TestIndexOf(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveOID(t *testing.T) {
+	resolver, err := LoadMIBs("test_mibs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var oids = map[string]string{
+		"1.3.6.1.4.1.98789.1":            "1.3.6.1.4.1.98789.1",
+		"TEST-MIB::testMIB":              "1.3.6.1.4.1.12345",
+		"testMIB":                        "1.3.6.1.4.1.12345",
+		"TEST-MIB::testCounter":          "1.3.6.1.4.1.12345.1.1",
+		"TEST-MIB::testCounter.0":        "1.3.6.1.4.1.12345.1.1.0",
+		"TEST-MIB::testAbsolute":         "1.3.6.42",
+		"TEST-MIB::testNotification":     "1.3.6.1.4.1.12345.2",
+		"TEST-MIB::testLegacyTrap":       "1.3.6.1.4.1.12345.0.3",
+		"TEST-MIB::testShared":           "1.3.6.1.4.1.12345.4",
+		"OTHER-TEST-MIB::testShared":     "1.3.6.1.4.1.12345.5",
+		"OTHER-TEST-MIB::testShared.1.2": "1.3.6.1.4.1.12345.5.1.2",
+		"enterprises.98789":              "1.3.6.1.4.1.98789",
+	}
+	for name, expected := range oids {
+		oid, err := resolver.ResolveOID(name)
+		if err != nil {
+			t.Errorf("unexpected error while resolving %s: %s", name, err)
+		} else if oid != expected {
+			t.Errorf("OID %s should be resolved as %s, got %s", name, expected, oid)
+		}
+	}
+}
+
+func TestResolveOIDErrors(t *testing.T) {
+	resolver, err := LoadMIBs("test_mibs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names = map[string]string{
+		"testCommented":            "unknown symbol testCommented in the loaded MIB modules",
+		"testOther":                "unknown symbol testOther in the loaded MIB modules",
+		"TEST-MIB::testUnknown":    "unknown symbol testUnknown in MIB module TEST-MIB",
+		"UNKNOWN-MIB::testMIB":     "unknown MIB module UNKNOWN-MIB in OID \"UNKNOWN-MIB::testMIB\"",
+		"testShared":               "ambiguous symbol testShared, defined in MIB modules OTHER-TEST-MIB, TEST-MIB: use MODULE::testShared",
+		"TEST-MIB::testOrphan":     "unable to resolve OID \"TEST-MIB::testOrphan\": unknown parent testMissing of TEST-MIB::testOrphan, its MIB module may be missing",
+		"TEST-MIB::testCounter.a":  "invalid OID provided: \"TEST-MIB::testCounter.a\"",
+		"TEST-MIB::testCounter.1.": "invalid OID provided: \"TEST-MIB::testCounter.1.\"",
+		"":                         "invalid OID provided: \"\"",
+		"TEST-MIB::":               "invalid OID provided: \"TEST-MIB::\"",
+	}
+	for name, expected := range names {
+		if _, err := resolver.ResolveOID(name); err == nil || err.Error() != expected {
+			t.Errorf("resolving %q should fail with %q, got %v", name, expected, err)
+		}
+	}
+
+	var noResolver *OIDResolver
+	if _, err := noResolver.ResolveOID("testMIB"); err == nil || err.Error() != "invalid OID provided: \"testMIB\"" {
+		t.Errorf("resolving a symbolic OID without MIB modules should fail, got %v", err)
+	}
+	if oid, err := noResolver.ResolveOID("1.3.6"); err != nil || oid != "1.3.6" {
+		t.Errorf("numeric OIDs should be resolved without MIB modules, got %s, %v", oid, err)
+	}
+}
+
+func TestResolveSNMPNotifierMIB(t *testing.T) {
+	resolver, err := LoadMIBs("../mibs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var oids = map[string]string{
+		"SNMP-NOTIFIER-MIB::snmpNotifierDefaultTrap":       "1.3.6.1.4.1.98789.1",
+		"SNMP-NOTIFIER-MIB::snmpNotifierAlertsObjects":     "1.3.6.1.4.1.98789.2",
+		"SNMP-NOTIFIER-MIB::snmpNotifierAlertsUserObjects": "1.3.6.1.4.1.98789.3",
+		"snmpNotifierAlertSeverityLevel":                   "1.3.6.1.4.1.98789.2.5",
+		"snmpNotifierAlertPartCount":                       "1.3.6.1.4.1.98789.2.7",
+		"snmpNotifierActiveAlertDescription":               "1.3.6.1.4.1.98789.4.1.1.5",
+		"snmpNotifierActiveAlertObjectValue":               "1.3.6.1.4.1.98789.4.2.1.2",
+		"SNMP-NOTIFIER-MIB::snmpNotifierHeartbeatTrap":     "1.3.6.1.4.1.98789.5",
+		"snmpNotifierHeartbeatFailedTraps":                 "1.3.6.1.4.1.98789.6.3",
+	}
+	for name, expected := range oids {
+		if oid, err := resolver.ResolveOID(name); err != nil || oid != expected {
+			t.Errorf("OID %s should be resolved as %s, got %s, %v", name, expected, oid, err)
+		}
+	}
+
+	if _, err := LoadMIBs("unknown_directory"); err == nil {
+		t.Error("loading an unknown directory should fail")
+	}
+}
+
+func TestTemplateFunctions(t *testing.T) {
+	object := struct {
+		Text     string
+		Empty    string
+		Number   float64
+		Count    int
+		StartsAt time.Time
+		Labels   map[string]string
+	}{
+		Text:     "  Disk usage on café-01 is high  ",
+		Number:   1234567,
+		Count:    12,
+		StartsAt: time.Date(2026, 10, 17, 8, 30, 0, 0, time.FixedZone("CEST", 2*60*60)),
+		Labels:   map[string]string{"severity": "critical", "alertname": "DiskFull", "instance": "node-1"},
+	}
+
+	var tests = []struct {
+		template string
+		result   string
+	}{
+		{`{{ .Text | trim | toUpper }}`, "DISK USAGE ON CAFÉ-01 IS HIGH"},
+		{`{{ .Text | trim | toLower }}`, "disk usage on café-01 is high"},
+		{`{{ .Text | trim | truncate 10 }}`, "Disk usage"},
+		{`{{ "café" | truncate 4 }}`, "caf"},
+		{`{{ "café" | truncate 10 }}`, "café"},
+		{`{{ .Text | trim | regexReplace "[0-9]+" "XX" }}`, "Disk usage on café-XX is high"},
+		{`{{ .Text | trim | ascii }}`, "Disk usage on cafe-01 is high"},
+		{`{{ "Straße, Ærø — 東" | ascii }}`, "Strasse, AEro - ?"},
+		{`{{ .StartsAt | formatTime "2006-01-02 15:04:05" }}`, "2026-10-17 06:30:00"},
+		{`{{ .Number | humanize }}`, "1.235M"},
+		{`{{ .Count | humanize }}`, "12"},
+		{`{{ "0.0025" | humanize }}`, "2.5m"},
+		{`{{ .Empty | default "none" }}`, "none"},
+		{`{{ .Labels.missing | default "none" }}`, "none"},
+		{`{{ .Labels.severity | default "none" }}`, "critical"},
+		{`{{ .Labels | sortedLabels | join ", " }}`, "alertname=DiskFull, instance=node-1, severity=critical"},
+	}
+	for _, test := range tests {
+		template, err := template.New(test.template).Funcs(TemplateFunctions()).Parse(test.template)
+		if err != nil {
+			t.Error("Unable to compile template", err)
+			continue
+		}
+		result, err := FillTemplate(object, *template)
+		if err != nil {
+			t.Errorf("FillTemplate of [%s] failed: %s", test.template, err)
+			continue
+		}
+		if *result != test.result {
+			t.Errorf("FillTemplate of [%s] shoud be [%s], got [%s]", test.template, test.result, *result)
+		}
+	}
+
+	since, err := template.New("since").Funcs(TemplateFunctions()).Parse(`{{ .StartsAt | since }}`)
+	if err != nil {
+		t.Fatal("Unable to compile template", err)
+	}
+	object.StartsAt = time.Now().Add(-90 * time.Second)
+	if result, _ := FillTemplate(object, *since); *result != "1m30s" {
+		t.Error("the duration since the start should be 1m30s, got", *result)
+	}
+}
+
+func TestTemplateFunctionErrors(t *testing.T) {
+	for _, text := range []string{
+		`{{ "text" | regexReplace "[" "" }}`,
+		`{{ "many" | humanize }}`,
+	} {
+		template, err := template.New(text).Funcs(TemplateFunctions()).Parse(text)
+		if err != nil {
+			t.Error("Unable to compile template", err)
+			continue
+		}
+		if _, err := FillTemplate(nil, *template); err == nil {
+			t.Errorf("FillTemplate of [%s] should fail", text)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commons
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// well-known OIDs defined by the ASN.1 and SNMPv2-SMI modules, so that MIB modules resolve without them
+var wellKnownOIDs = map[string]string{
+	"ccitt":           "0",
+	"zeroDotZero":     "0.0",
+	"iso":             "1",
+	"org":             "1.3",
+	"dod":             "1.3.6",
+	"internet":        "1.3.6.1",
+	"directory":       "1.3.6.1.1",
+	"mgmt":            "1.3.6.1.2",
+	"mib-2":           "1.3.6.1.2.1",
+	"transmission":    "1.3.6.1.2.1.10",
+	"experimental":    "1.3.6.1.3",
+	"private":         "1.3.6.1.4",
+	"enterprises":     "1.3.6.1.4.1",
+	"security":        "1.3.6.1.5",
+	"snmpV2":          "1.3.6.1.6",
+	"snmpDomains":     "1.3.6.1.6.1",
+	"snmpProxys":      "1.3.6.1.6.2",
+	"snmpModules":     "1.3.6.1.6.3",
+	"joint-iso-ccitt": "2",
+}
+
+// macros assigning an OID to the name preceding them
+var oidMacros = []string{
+	"OBJECT-TYPE",
+	"OBJECT-IDENTITY",
+	"MODULE-IDENTITY",
+	"NOTIFICATION-TYPE",
+	"OBJECT-GROUP",
+	"NOTIFICATION-GROUP",
+	"MODULE-COMPLIANCE",
+	"AGENT-CAPABILITIES",
+	"TRAP-TYPE",
+}
+
+// OIDResolver resolves symbolic OIDs, such as SNMP-NOTIFIER-MIB::snmpNotifierDefaultTrap, from MIB modules
+type OIDResolver struct {
+	modules map[string]map[string]oidDefinition
+}
+
+// oidDefinition is an OID relative to a parent symbol, or absolute when the parent is empty
+type oidDefinition struct {
+	parent string
+	subIDs []string
+}
+
+// LoadMIBs loads the MIB modules of the files of a directory
+func LoadMIBs(directory string) (*OIDResolver, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := &OIDResolver{modules: map[string]map[string]oidDefinition{}}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(directory, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for module, definitions := range parseMIB(string(content)) {
+			resolver.modules[module] = definitions
+		}
+	}
+	return resolver, nil
+}
+
+// ResolveOID returns the numeric OID of a symbolic OID, written MODULE::symbol or symbol, optionally followed by
+// numeric sub-identifiers. Numeric OIDs are returned as is.
+func (resolver *OIDResolver) ResolveOID(name string) (string, error) {
+	if IsOID(name) {
+		return name, nil
+	}
+	if resolver == nil {
+		return "", fmt.Errorf("invalid OID provided: \"%s\"", name)
+	}
+
+	module, symbol, qualified := strings.Cut(name, "::")
+	if !qualified {
+		module, symbol = "", name
+	}
+	symbol, suffix, _ := strings.Cut(symbol, ".")
+	if symbol == "" || suffix != "" && !IsOID(suffix) {
+		return "", fmt.Errorf("invalid OID provided: \"%s\"", name)
+	}
+
+	if qualified {
+		definitions, found := resolver.modules[module]
+		if !found {
+			return "", fmt.Errorf("unknown MIB module %s in OID \"%s\"", module, name)
+		}
+		if _, found := definitions[symbol]; !found {
+			return "", fmt.Errorf("unknown symbol %s in MIB module %s", symbol, module)
+		}
+	} else {
+		modules := resolver.modulesDefining(symbol)
+		if len(modules) > 1 {
+			return "", fmt.Errorf("ambiguous symbol %s, defined in MIB modules %s: use MODULE::%s", symbol, strings.Join(modules, ", "), symbol)
+		}
+		if len(modules) == 0 {
+			if oid, found := wellKnownOIDs[symbol]; found {
+				return joinOID(oid, suffix), nil
+			}
+			return "", fmt.Errorf("unknown symbol %s in the loaded MIB modules", symbol)
+		}
+		module = modules[0]
+	}
+
+	oid, err := resolver.resolve(module, symbol, map[string]bool{})
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve OID \"%s\": %w", name, err)
+	}
+	return joinOID(oid, suffix), nil
+}
+
+func (resolver *OIDResolver) resolve(module string, symbol string, visited map[string]bool) (string, error) {
+	key := module + "::" + symbol
+	if visited[key] {
+		return "", fmt.Errorf("circular definition of %s", key)
+	}
+	visited[key] = true
+
+	definition := resolver.modules[module][symbol]
+	if definition.parent == "" {
+		return strings.Join(definition.subIDs, "."), nil
+	}
+
+	var parent string
+	if _, found := resolver.modules[module][definition.parent]; found {
+		oid, err := resolver.resolve(module, definition.parent, visited)
+		if err != nil {
+			return "", err
+		}
+		parent = oid
+	} else if oid, found := wellKnownOIDs[definition.parent]; found {
+		parent = oid
+	} else if modules := resolver.modulesDefining(definition.parent); len(modules) > 0 {
+		oid, err := resolver.resolve(modules[0], definition.parent, visited)
+		if err != nil {
+			return "", err
+		}
+		parent = oid
+	} else {
+		return "", fmt.Errorf("unknown parent %s of %s, its MIB module may be missing", definition.parent, key)
+	}
+	return joinOID(parent, strings.Join(definition.subIDs, ".")), nil
+}
+
+func (resolver *OIDResolver) modulesDefining(symbol string) []string {
+	modules := []string{}
+	for module, definitions := range resolver.modules {
+		if _, found := definitions[symbol]; found {
+			modules = append(modules, module)
+		}
+	}
+	slices.Sort(modules)
+	return modules
+}
+
+func joinOID(oid string, suffix string) string {
+	if suffix == "" {
+		return oid
+	}
+	return oid + "." + suffix
+}
+
+// parseMIB extracts the OID definitions of the MIB modules of a file, indexed by module name
+func parseMIB(content string) map[string]map[string]oidDefinition {
+	tokens := tokenizeMIB(content)
+	modules := map[string]map[string]oidDefinition{}
+
+	var definitions map[string]oidDefinition
+	for index := 0; index < len(tokens); index++ {
+		token := tokens[index]
+		switch {
+		case token == "DEFINITIONS" && index > 0:
+			definitions = map[string]oidDefinition{}
+			modules[tokens[index-1]] = definitions
+		case token == "MACRO":
+			// macro definitions, like the ones of SNMPv2-SMI, embed BEGIN and END keywords
+			for index < len(tokens) && tokens[index] != "END" {
+				index++
+			}
+		case definitions == nil || !isValueName(token) || index+1 >= len(tokens):
+			continue
+		case tokens[index+1] == "OBJECT" && index+3 < len(tokens) && tokens[index+2] == "IDENTIFIER" && tokens[index+3] == "::=":
+			if definition, ok := parseOIDValue(tokens, index+4); ok {
+				definitions[token] = definition
+			}
+		case slices.Contains(oidMacros, tokens[index+1]):
+			if definition, ok := parseMacroValue(tokens, index+1); ok {
+				definitions[token] = definition
+			}
+		}
+	}
+	return modules
+}
+
+// parseMacroValue parses the value assigned at the end of a macro invocation
+func parseMacroValue(tokens []string, index int) (oidDefinition, bool) {
+	macro := tokens[index]
+	enterprise := ""
+	for ; index < len(tokens) && tokens[index] != "::="; index++ {
+		if tokens[index] == "ENTERPRISE" && index+1 < len(tokens) {
+			enterprise = tokens[index+1]
+		}
+	}
+	if macro == "TRAP-TYPE" {
+		// SMIv1 traps are converted into SMIv2 notifications according to RFC 3584
+		if index+1 >= len(tokens) || enterprise == "" || !IsOID(tokens[index+1]) {
+			return oidDefinition{}, false
+		}
+		return oidDefinition{parent: enterprise, subIDs: []string{"0", tokens[index+1]}}, true
+	}
+	return parseOIDValue(tokens, index+1)
+}
+
+// parseOIDValue parses an OID value, such as { iso org(3) 6 } or { snmpNotifier 1 }
+func parseOIDValue(tokens []string, index int) (oidDefinition, bool) {
+	if index >= len(tokens) || tokens[index] != "{" {
+		return oidDefinition{}, false
+	}
+
+	definition := oidDefinition{}
+	first := true
+	for index++; index < len(tokens) && tokens[index] != "}"; index++ {
+		token := tokens[index]
+		switch {
+		case IsOID(token):
+			definition.subIDs = append(definition.subIDs, token)
+		case isValueName(token) && index+3 < len(tokens) && tokens[index+1] == "(" && tokens[index+3] == ")":
+			definition.subIDs = append(definition.subIDs, tokens[index+2])
+			index += 3
+		case isValueName(token) && first:
+			definition.parent = token
+		default:
+			return oidDefinition{}, false
+		}
+		first = false
+	}
+	return definition, index < len(tokens) && (definition.parent != "" || len(definition.subIDs) > 0)
+}
+
+func isValueName(token string) bool {
+	return token != "" && unicode.IsLower(rune(token[0]))
+}
+
+// tokenizeMIB splits a MIB module into tokens, without its comments and quoted strings
+func tokenizeMIB(content string) []string {
+	tokens := []string{}
+	for index := 0; index < len(content); {
+		character := content[index]
+		switch {
+		case strings.HasPrefix(content[index:], "--"):
+			// comments end with the line, or with another --
+			end := index + 2
+			for end < len(content) && content[end] != '\n' && !strings.HasPrefix(content[end:], "--") {
+				end++
+			}
+			index = min(end+2, len(content))
+			if end < len(content) && content[end] == '\n' {
+				index = end + 1
+			}
+		case character == '"':
+			end := strings.IndexByte(content[index+1:], '"')
+			if end < 0 {
+				return tokens
+			}
+			index += end + 2
+		case strings.HasPrefix(content[index:], "::="):
+			tokens = append(tokens, "::=")
+			index += 3
+		case isMIBIdentifierCharacter(character):
+			end := index
+			for end < len(content) && (isMIBIdentifierCharacter(content[end]) || content[end] == '-' && !strings.HasPrefix(content[end:], "--")) {
+				end++
+			}
+			tokens = append(tokens, content[index:end])
+			index = end
+		case unicode.IsSpace(rune(character)):
+			index++
+		default:
+			tokens = append(tokens, string(character))
+			index++
+		}
+	}
+	return tokens
+}
+
+func isMIBIdentifierCharacter(character byte) bool {
+	return character == '_' || character >= '0' && character <= '9' || character >= 'a' && character <= 'z' || character >= 'A' && character <= 'Z'
+}
@@ -0,0 +1,198 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commons
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// letters that do not decompose into an ASCII letter and a diacritic
+var asciiTransliterations = map[rune]string{
+	'ß': "ss", 'Æ': "AE", 'æ': "ae", 'Œ': "OE", 'œ': "oe", 'Ø': "O", 'ø': "o", 'Đ': "D", 'đ': "d",
+	'Ð': "D", 'ð': "d", 'Þ': "TH", 'þ': "th", 'Ł': "L", 'ł': "l", 'ı': "i",
+	'‘': "'", '’': "'", '“': "\"", '”': "\"", '–': "-", '—': "-", '…': "...",
+}
+
+// TemplateFunctions returns the functions available to the description and user object templates
+func TemplateFunctions() template.FuncMap {
+	return template.FuncMap{
+		"groupAlertsByLabel":  GroupAlertsByLabel,
+		"groupAlertsByName":   GroupAlertsByName,
+		"groupAlertsByStatus": GroupAlertsByStatus,
+		"toUpper":             strings.ToUpper,
+		"toLower":             strings.ToLower,
+		"trim":                strings.TrimSpace,
+		"truncate":            Truncate,
+		"join":                join,
+		"regexReplace":        regexReplace,
+		"formatTime":          formatTime,
+		"since":               since,
+		"humanize":            humanize,
+		"ascii":               ascii,
+		"default":             defaultValue,
+		"sortedLabels":        sortedLabels,
+	}
+}
+
+// ParseTemplateFile parses a template file, with the template functions
+func ParseTemplateFile(path string) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Funcs(TemplateFunctions()).ParseFiles(path)
+}
+
+// Truncate shortens a text to a maximum number of bytes, without splitting a character
+func Truncate(length int, text string) string {
+	if length < 0 || len(text) <= length {
+		return text
+	}
+	for length > 0 && !utf8.RuneStart(text[length]) {
+		length--
+	}
+	return text[:length]
+}
+
+func join(separator string, elements []string) string {
+	return strings.Join(elements, separator)
+}
+
+func regexReplace(pattern string, replacement string, text string) (string, error) {
+	expression, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return expression.ReplaceAllString(text, replacement), nil
+}
+
+// formatTime formats a time, such as the StartsAt of an alert, with a Go layout, in UTC
+func formatTime(layout string, value time.Time) string {
+	return value.UTC().Format(layout)
+}
+
+// since returns the time elapsed since a given time, such as the StartsAt of an alert, to the second
+func since(value time.Time) time.Duration {
+	return time.Since(value).Truncate(time.Second)
+}
+
+// humanize writes a number with an SI prefix, e.g. 1.235k for 1234.5
+func humanize(value any) (string, error) {
+	number, err := toFloat64(value)
+	if err != nil {
+		return "", err
+	}
+	if number == 0 || math.IsNaN(number) || math.IsInf(number, 0) {
+		return fmt.Sprintf("%.4g", number), nil
+	}
+
+	if math.Abs(number) >= 1 {
+		prefix := ""
+		for _, largerPrefix := range []string{"k", "M", "G", "T", "P", "E", "Z", "Y"} {
+			if math.Abs(number) < 1000 {
+				break
+			}
+			prefix = largerPrefix
+			number /= 1000
+		}
+		return fmt.Sprintf("%.4g%s", number, prefix), nil
+	}
+
+	prefix := ""
+	for _, smallerPrefix := range []string{"m", "u", "n", "p", "f", "a", "z", "y"} {
+		if math.Abs(number) >= 1 {
+			break
+		}
+		prefix = smallerPrefix
+		number *= 1000
+	}
+	return fmt.Sprintf("%.4g%s", number, prefix), nil
+}
+
+func toFloat64(value any) (float64, error) {
+	switch number := value.(type) {
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(number), 64)
+	case time.Duration:
+		return number.Seconds(), nil
+	}
+
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(reflected.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(reflected.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return reflected.Float(), nil
+	}
+	return 0, fmt.Errorf("not a number: %v", value)
+}
+
+// ascii transliterates a text into ASCII, removing the diacritics, e.g. Ärger becomes Arger. The characters that cannot
+// be transliterated are replaced with a question mark.
+func ascii(text string) string {
+	builder := strings.Builder{}
+	for _, character := range norm.NFKD.String(text) {
+		switch {
+		case character < utf8.RuneSelf:
+			builder.WriteRune(character)
+		case unicode.Is(unicode.Mn, character):
+			// diacritics, separated from their letter by the decomposition
+		case asciiTransliterations[character] != "":
+			builder.WriteString(asciiTransliterations[character])
+		default:
+			builder.WriteRune('?')
+		}
+	}
+	return builder.String()
+}
+
+// defaultValue returns the given value, or the default one when it is empty
+func defaultValue(fallback any, value any) any {
+	if value == nil {
+		return fallback
+	}
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.String, reflect.Map, reflect.Slice, reflect.Array:
+		if reflected.Len() == 0 {
+			return fallback
+		}
+	case reflect.Pointer, reflect.Interface:
+		if reflected.IsNil() {
+			return fallback
+		}
+	}
+	return value
+}
+
+// sortedLabels returns the name=value pairs of labels, sorted by name
+func sortedLabels(labels map[string]string) []string {
+	pairs := make([]string, 0, len(labels))
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, name+"="+labels[name])
+	}
+	return pairs
+}
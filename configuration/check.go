@@ -0,0 +1,51 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configuration
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/maxwo/snmp_notifier/alertparser"
+	"github.com/maxwo/snmp_notifier/trapsender"
+	"github.com/maxwo/snmp_notifier/types"
+)
+
+// sampleAlerts is an Alertmanager notification with firing and resolved alerts, used to check the templates
+//
+//go:embed sample_alerts.json
+var sampleAlerts []byte
+
+// CheckTemplates renders the templates of a configuration against sample alerts, and reports all the errors
+func CheckTemplates(configuration SNMPNotifierConfiguration, logger *slog.Logger) error {
+	alertsData := types.AlertsData{}
+	if err := json.Unmarshal(sampleAlerts, &alertsData); err != nil {
+		return fmt.Errorf("invalid sample alerts: %w", err)
+	}
+
+	// the sample alerts take the configured severities in turn, so that they are all rendered
+	alertParserConfiguration := configuration.AlertParserConfiguration
+	for index, alert := range alertsData.Alerts {
+		alert.Labels[alertParserConfiguration.SeverityLabel] = alertParserConfiguration.Severities[index%len(alertParserConfiguration.Severities)]
+	}
+
+	alertBucket, err := alertparser.New(alertParserConfiguration, logger).Parse(alertsData)
+	if err != nil {
+		return fmt.Errorf("unable to parse the sample alerts: %w", err)
+	}
+
+	return trapsender.CheckTemplates(configuration.TrapSenderConfiguration, *alertBucket)
+}
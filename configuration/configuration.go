@@ -14,12 +14,16 @@
 package configuration
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"net"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"text/template"
+	"time"
 
 	"log/slog"
 
@@ -29,8 +33,11 @@ import (
 	"github.com/shirou/gopsutil/host"
 
 	"github.com/maxwo/snmp_notifier/alertparser"
+	"github.com/maxwo/snmp_notifier/alertstore"
 	"github.com/maxwo/snmp_notifier/commons"
+	"github.com/maxwo/snmp_notifier/heartbeat"
 	"github.com/maxwo/snmp_notifier/httpserver"
+	"github.com/maxwo/snmp_notifier/snmpagent"
 	"github.com/maxwo/snmp_notifier/trapsender"
 
 	"strconv"
@@ -39,11 +46,42 @@ import (
 	"github.com/prometheus/common/version"
 )
 
+// Commands of the SNMP notifier
+const (
+	// RunCommand relays the alerts as SNMP traps, it is the default command
+	RunCommand = "run"
+	// GenerateMIBCommand prints a MIB module describing the configured user objects
+	GenerateMIBCommand = "generate-mib"
+	// CheckConfigCommand validates the configuration and renders the templates, without starting the server
+	CheckConfigCommand = "check-config"
+	// RenderCommand prints the traps generated from an Alertmanager notification, without sending them
+	RenderCommand = "render"
+)
+
+// Output formats of the render command
+const (
+	TextRenderFormat = "text"
+	JSONRenderFormat = "json"
+)
+
+// Command is the command given on the command line, with its own arguments
+type Command struct {
+	Name          string
+	MIBModuleName string
+	MIBModuleOID  string
+	MIBOutput     string
+	AlertsFile    string
+	RenderFormat  string
+}
+
 // SNMPNotifierConfiguration handles the configuration of the whole application
 type SNMPNotifierConfiguration struct {
 	AlertParserConfiguration alertparser.Configuration
 	TrapSenderConfiguration  trapsender.Configuration
 	HTTPServerConfiguration  httpserver.Configuration
+	AlertStoreConfiguration  alertstore.Configuration
+	AgentConfiguration       snmpagent.Configuration
+	HeartbeatConfiguration   heartbeat.Configuration
 }
 
 var (
@@ -51,41 +89,97 @@ var (
 	snmpAuthUsernameEnvironmentVariable = "SNMP_NOTIFIER_AUTH_USERNAME"
 	snmpAuthPasswordEnvironmentVariable = "SNMP_NOTIFIER_AUTH_PASSWORD"
 	snmpPrivPasswordEnvironmentVariable = "SNMP_NOTIFIER_PRIV_PASSWORD"
+	agentCommunityEnvironmentVariable   = "SNMP_NOTIFIER_AGENT_COMMUNITY"
+
+	snmpVersions                = []string{"V1", "V2c", "V3"}
+	snmpAuthenticationProtocols = []string{"MD5", "SHA", "SHA224", "SHA256", "SHA384", "SHA512"}
+	snmpPrivateProtocols        = []string{"DES", "AES", "AES192", "AES256", "AES192C", "AES256C"}
+
+	trapGroupings = []string{alertparser.PerOIDGrouping, alertparser.PerAlertGrouping}
+	trapAlertIDs  = []string{alertparser.KeyAlertID, alertparser.HashAlertID}
 )
 
 // ParseConfiguration parses the command line for configurations
 func ParseConfiguration(args []string) (*SNMPNotifierConfiguration, *slog.Logger, error) {
+	_, configuration, logger, err := ParseCommandLine(args)
+	return configuration, logger, err
+}
+
+// ParseCommandLine parses the command line for the command to run and the configurations
+func ParseCommandLine(args []string) (*Command, *SNMPNotifierConfiguration, *slog.Logger, error) {
+	var snmpDestinationSetByUser bool
+
 	var (
 		application          = kingpin.New("snmp_notifier", "A tool to relay Prometheus alerts as SNMP traps")
 		toolKitConfiguration = kingpinflag.AddFlags(application, ":9464")
 
-		alertSeverityLabel   = application.Flag("alert.severity-label", "Label where to find the alert severity.").Default("severity").String()
-		alertSeverities      = application.Flag("alert.severities", "The ordered list of alert severities, from more priority to less priority.").Default("critical,warning,info").String()
-		alertDefaultSeverity = application.Flag("alert.default-severity", "The alert severity if none is provided via labels.").Default("critical").String()
+		_                     = application.Command(RunCommand, "Relay the Prometheus alerts as SNMP traps.").Default()
+		_                     = application.Command(CheckConfigCommand, "Validate the configuration, and render the templates against sample alerts, without starting the server.")
+		generateMIBCommand    = application.Command(GenerateMIBCommand, "Print a MIB module describing the configured user objects, and the traps carrying them.")
+		generateMIBModuleName = generateMIBCommand.Flag("module-name", "Name of the generated MIB module.").Default("SNMP-NOTIFIER-USER-MIB").String()
+		generateMIBModuleOID  = generateMIBCommand.Flag("module-oid", "OID of the identity of the generated MIB module.").Default("1.3.6.1.4.1.98789.100").String()
+		generateMIBOutput     = generateMIBCommand.Flag("output", "File where the MIB module is written. Defaults to the standard output.").PlaceHolder("FILE").String()
+		renderCommand         = application.Command(RenderCommand, "Print the traps generated from an Alertmanager webhook notification, without sending them.")
+		renderAlertsFile      = renderCommand.Arg("alerts-file", "JSON file of the Alertmanager webhook notification.").Required().ExistingFile()
+		renderFormat          = renderCommand.Flag("format", "Output format, text or json.").Default(TextRenderFormat).Enum(TextRenderFormat, JSONRenderFormat)
+
+		alertSeverityLabel    = application.Flag("alert.severity-label", "Label where to find the alert severity.").Default("severity").String()
+		alertSeverities       = application.Flag("alert.severities", "The ordered list of alert severities, from more priority to less priority.").Default("critical,warning,info").String()
+		alertSeverityLevel    = application.Flag("alert.severity-level", "Numeric level of a severity sent in the traps, e.g. --alert.severity-level=critical=5. When not given, the levels follow the order of the severities, starting with 1. When given, every severity needs a positive level.").PlaceHolder("critical=1").StringMap()
+		alertDefaultSeverity  = application.Flag("alert.default-severity", "The alert severity if none is provided via labels.").Default("critical").String()
+		alertRenotifyInterval = application.Flag("alert.renotify-interval", "Interval between two notifications of the firing alert groups of a severity, e.g. --alert.renotify-interval=critical=5m, until they are resolved. The groups of the severities without interval are not re-notified. You may give an interval to several severities using that flag several times.").PlaceHolder("critical=5m").StringMap()
+		alertStateFile        = application.Flag("alert.state-file", "File where the firing alert groups are persisted, to keep re-notifying them after a restart.").PlaceHolder("FILE").String()
+		alertMaxAge           = application.Flag("alert.max-age", "Maximum duration a firing alert group is kept without being notified again by Alertmanager, e.g. when its resolution is not sent. Greater than the repeat_interval of Alertmanager. Unlimited when 0.").Default("24h").Duration()
 
 		// SNMP configuration
-		snmpVersion     = application.Flag("snmp.version", "SNMP version. V2c and V3 are currently supported.").Default("V2c").HintOptions("V2c", "V3").Enum("V2c", "V3")
-		snmpDestination = application.Flag("snmp.destination", "SNMP trap server destination.").Default("127.0.0.1:162").TCPList()
+		snmpVersion     = application.Flag("snmp.version", "SNMP version. V1, V2c and V3 are currently supported.").Default("V2c").HintOptions(snmpVersions...).Enum(snmpVersions...)
+		snmpDestination = application.Flag("snmp.destination", "SNMP trap server destination. Destinations with their own version and credentials may be defined in the configuration file.").Default("127.0.0.1:162").IsSetByUser(&snmpDestinationSetByUser).TCPList()
 		snmpRetries     = application.Flag("snmp.retries", "SNMP number of retries").Default("1").Uint()
 		snmpTimeout     = application.Flag("snmp.timeout", "SNMP timeout duration").Default("5s").Duration()
+		snmpInform      = application.Flag("snmp.inform", "Send InformRequest PDUs instead of traps, and wait for the SNMP server to acknowledge them. Unacknowledged informs are retried, then reported as failures to Alertmanager.").Default("false").Bool()
+
+		// V1 and V2c only
+		snmpCommunity = application.Flag("snmp.community", "SNMP community (V1 and V2c only). Passing secrets to the command line is not recommended, consider using the SNMP_NOTIFIER_COMMUNITY environment variable instead.").Envar(snmpCommunityEnvironmentVariable).Default("public").String()
 
-		// V2c only
-		snmpCommunity = application.Flag("snmp.community", "SNMP community (V2c only). Passing secrets to the command line is not recommended, consider using the SNMP_NOTIFIER_COMMUNITY environment variable instead.").Envar(snmpCommunityEnvironmentVariable).Default("public").String()
+		// V1 only
+		snmpAgentAddress = application.Flag("snmp.agent-address", "IPv4 address sent as the agent address of the traps (V1 only).").Default("0.0.0.0").String()
 
 		// V3 only
 		snmpAuthenticationEnabled  = application.Flag("snmp.authentication-enabled", "Enable SNMP authentication (V3 only).").Default("false").Bool()
-		snmpAuthenticationProtocol = application.Flag("snmp.authentication-protocol", "Protocol for password encryption (V3 only). MD5 and SHA are currently supported.").Default("MD5").HintOptions("MD5", "SHA").Enum("MD5", "SHA")
+		snmpAuthenticationProtocol = application.Flag("snmp.authentication-protocol", "Protocol for password encryption (V3 only). MD5, SHA, SHA224, SHA256, SHA384 and SHA512 are currently supported.").Default("MD5").HintOptions(snmpAuthenticationProtocols...).Enum(snmpAuthenticationProtocols...)
 		snmpAuthenticationUsername = application.Flag("snmp.authentication-username", "SNMP authentication username (V3 only). Passing secrets to the command line is not recommended, consider using the SNMP_NOTIFIER_AUTH_USERNAME environment variable instead.").PlaceHolder("USERNAME").Envar(snmpAuthUsernameEnvironmentVariable).String()
 		snmpAuthenticationPassword = application.Flag("snmp.authentication-password", "SNMP authentication password (V3 only). Passing secrets to the command line is not recommended, consider using the SNMP_NOTIFIER_AUTH_PASSWORD environment variable instead.").PlaceHolder("PASSWORD").Envar(snmpAuthPasswordEnvironmentVariable).String()
 		snmpPrivateEnabled         = application.Flag("snmp.private-enabled", "Enable SNMP encryption (V3 only).").Default("false").Bool()
-		snmpPrivateProtocol        = application.Flag("snmp.private-protocol", "Protocol for SNMP data transmission (V3 only). DES and AES are currently supported.").Default("DES").HintOptions("DES", "AES").Enum("DES", "AES")
+		snmpPrivateProtocol        = application.Flag("snmp.private-protocol", "Protocol for SNMP data transmission (V3 only). DES, AES, AES192, AES256, AES192C and AES256C are currently supported.").Default("DES").HintOptions(snmpPrivateProtocols...).Enum(snmpPrivateProtocols...)
 		snmpPrivatePassword        = application.Flag("snmp.private-password", "SNMP private password (V3 only). Passing secrets to the command line is not recommended, consider using the SNMP_NOTIFIER_PRIV_PASSWORD environment variable instead.").PlaceHolder("SECRET").Envar(snmpPrivPasswordEnvironmentVariable).String()
 		snmpSecurityEngineID       = application.Flag("snmp.security-engine-id", "SNMP security engine ID (V3 only).").PlaceHolder("SECURITY_ENGINE_ID").String()
 		snmpContextEngineID        = application.Flag("snmp.context-engine-id", "SNMP context engine ID (V3 only).").PlaceHolder("CONTEXT_ENGINE_ID").String()
 		snmpContextName            = application.Flag("snmp.context-name", "SNMP context name (V3 only).").PlaceHolder("CONTEXT_ENGINE_NAME").String()
 		snmpEngineStartTime        = application.Flag("snmp.engine-start-time", "UNIX timestamp specifying the engine start time in seconds. Defaults to the host boot time.").Default("").String()
 
+		// Asynchronous delivery
+		snmpQueueSize            = application.Flag("snmp.queue-size", "Number of traps queued for each destination. When greater than 0, traps are sent asynchronously, and Alertmanager notifications are answered with a 202 status once queued.").Default("0").Int()
+		snmpQueueWorkers         = application.Flag("snmp.queue-workers", "Number of workers sending the queued traps of each destination.").Default("1").Int()
+		snmpQueueRetries         = application.Flag("snmp.queue-retries", "Number of retries of the queued traps that failed to be sent.").Default("3").Uint()
+		snmpQueueRetryBackoff    = application.Flag("snmp.queue-retry-backoff", "Delay before retrying a queued trap, doubled after each retry.").Default("1s").Duration()
+		snmpQueueMaxRetryBackoff = application.Flag("snmp.queue-max-retry-backoff", "Maximum delay between two retries of a queued trap.").Default("1m").Duration()
+
+		snmpSendTimeout = application.Flag("snmp.send-timeout", "Maximum duration of the sending of the traps of a notification, to all destinations concurrently. Unlimited when 0.").Default("30s").Duration()
+
+		snmpMaxVarBindSize = application.Flag("snmp.max-varbind-size", "Maximum size of the string objects of the traps, e.g. 1KiB. Longer descriptions only describe the first alerts of the trap, and the other objects are truncated. Unlimited when 0.").Default("0").Bytes()
+		snmpMaxPDUSize     = application.Flag("snmp.max-pdu-size", "Maximum size of the PDU of the traps, without the SNMP message header, e.g. 1400B. The description is truncated to fit, and the traps that still exceed it fail. Unlimited when 0.").Default("0").Bytes()
+
+		snmpDryRun     = application.Flag("snmp.dry-run", "Log the traps instead of sending them, e.g. for staging environments. The traps are counted with the dry_run outcome.").Default("false").Bool()
+		snmpDryRunFile = application.Flag("snmp.dry-run-file", "File where the traps of a dry run are appended as JSON lines, instead of being logged.").PlaceHolder("FILE").String()
+
+		// Spool of undelivered traps
+		snmpSpoolDirectory      = application.Flag("snmp.spool-directory", "Directory where the traps that failed to be sent are persisted, and replayed once their destination is reachable again. Disabled when empty.").PlaceHolder("SPOOL_DIRECTORY").String()
+		snmpSpoolMaxAge         = application.Flag("snmp.spool-max-age", "Maximum age of the spooled traps, older traps are dropped. Unlimited when 0.").Default("24h").Duration()
+		snmpSpoolMaxSize        = application.Flag("snmp.spool-max-size", "Maximum size of the spool of each destination, the oldest traps are dropped beyond. Unlimited when 0.").Default("100MiB").Bytes()
+		snmpSpoolReplayInterval = application.Flag("snmp.spool-replay-interval", "Interval between two attempts to replay the spooled traps.").Default("30s").Duration()
+
 		// Trap configurations
+		trapMIBDirectory          = application.Flag("trap.mib-directory", "Directory of MIB modules, loaded to accept symbolic OIDs such as SNMP-NOTIFIER-MIB::snmpNotifierDefaultTrap in the OID flags and labels.").PlaceHolder("MIB_DIRECTORY").String()
 		trapDefaultOID            = application.Flag("trap.default-oid", "Default trap OID.").Default("1.3.6.1.4.1.98789.1").String()
 		trapOIDLabel              = application.Flag("trap.oid-label", "Label containing a custom trap OID.").Default("oid").String()
 		trapResolutionDefaultOID  = application.Flag("trap.resolution-default-oid", "Resolution trap OID, if different from the firing trap OID.").String()
@@ -93,27 +187,99 @@ func ParseConfiguration(args []string) (*SNMPNotifierConfiguration, *slog.Logger
 		trapDefaultObjectsBaseOID = application.Flag("trap.default-objects-base-oid", "Base OID for default trap objects.").Default("1.3.6.1.4.1.98789.2").String()
 		trapDescriptionTemplate   = application.Flag("trap.description-template", "Trap description template.").Default("description-template.tpl").ExistingFile()
 		trapUserObjectsBaseOID    = application.Flag("trap.user-objects-base-oid", "Base OID for user-defined trap objects.").Default("1.3.6.1.4.1.98789.3").String()
-		trapUserObject            = application.Flag("trap.user-object", "User object sub-OID and template, e.g. --trap.user-object=4=new-object.template.tpl to add a sub-object to the trap, with the given template file. You may add several user objects using that flag several times.").PlaceHolder("4=user-object-template.tpl").StringMap()
+		trapGrouping              = application.Flag("trap.grouping", "Grouping of the alerts into traps: per-oid sends one trap for the alerts sharing a trap OID, per-alert sends one trap per alert.").Default(alertparser.PerOIDGrouping).HintOptions(trapGroupings...).Enum(trapGroupings...)
+		trapAlertID               = application.Flag("trap.alert-id", "Alert ID of the traps: key is the grouping key of the alerts, hash is a hash of that key. With the per-alert grouping, the grouping key is the alert fingerprint.").Default(alertparser.KeyAlertID).HintOptions(trapAlertIDs...).Enum(trapAlertIDs...)
+		trapDescriptionType       = application.Flag("trap.description-type", "SNMP type of the trap description. string, integer, counter32, gauge32, counter64, timeticks, ipaddress and oid are currently supported.").Default(trapsender.StringObjectType).HintOptions(trapsender.ObjectTypes...).Enum(trapsender.ObjectTypes...)
+		trapUserObject            = application.Flag("trap.user-object", "User object sub-OID, optional SNMP type and template, e.g. --trap.user-object=4=new-object.template.tpl to add a sub-object to the trap, with the given template file, or --trap.user-object=5=integer:new-number.template.tpl to add an integer sub-object. The types are the ones of --trap.description-type. You may add several user objects using that flag several times.").PlaceHolder("4=[TYPE:]user-object-template.tpl").StringMap()
+		trapMaxAlertsPerTrap      = application.Flag("trap.max-alerts-per-trap", "Maximum number of alerts per trap. The alert groups with more alerts are split into several traps, sharing their alert ID, and carrying their part index and count. Unlimited when 0.").Default("0").Int()
+
+		// Heartbeat traps
+		heartbeatInterval = application.Flag("heartbeat.interval", "Interval between two heartbeat traps, sent to every destination with the uptime and the number of traps sent and failed since the start, so that the SNMP managers may raise an alarm when they stop. Disabled when 0.").Default("0").Duration()
+		heartbeatOID      = application.Flag("heartbeat.oid", "OID of the heartbeat traps.").Default("1.3.6.1.4.1.98789.5").String()
+
+		// SNMP agent exposing the active alerts
+		agentListenAddress = application.Flag("agent.listen-address", "UDP address on which an SNMP agent answers the requests on the active alerts table of SNMP-NOTIFIER-MIB, e.g. :1161. Disabled when empty.").PlaceHolder("ADDRESS").String()
+		agentCommunity     = application.Flag("agent.community", "SNMP community of the requests to the agent (V1 and V2c only). Passing secrets to the command line is not recommended, consider using the SNMP_NOTIFIER_AGENT_COMMUNITY environment variable instead.").Envar(agentCommunityEnvironmentVariable).Default("public").String()
 	)
 
+	application.Flag(configurationFileFlag, "YAML configuration file. Command line flags and environment variables take precedence over its values.").PlaceHolder("CONFIG_FILE").String()
+
 	promslogConfig := &promslog.Config{}
 	flag.AddFlags(application, promslogConfig)
 
+	var (
+		configurationFile    *fileConfiguration
+		configurationFileErr error
+	)
+	if configurationFileName := getConfigurationFileName(args); configurationFileName != "" {
+		configurationFile, configurationFileErr = loadConfigurationFile(configurationFileName)
+		if configurationFileErr == nil {
+			for flagName, values := range configurationFile.flagDefaults() {
+				if flagClause := application.GetFlag(flagName); flagClause != nil {
+					flagClause.Default(values...)
+				}
+			}
+		}
+	}
+
 	application.Version(version.Print("snmp_notifier"))
 	application.HelpFlag.Short('h')
-	kingpin.MustParse(application.Parse(args))
+	commandName, err := application.Parse(args)
+	if err != nil {
+		return nil, nil, promslog.New(promslogConfig), err
+	}
 
 	logger := promslog.New(promslogConfig)
-	logger.Info("Starting snmp_notifier", "version", version.Info())
-	logger.Info("Build context", "build_context", version.BuildContext())
-
-	descriptionTemplate, err := template.New(filepath.Base(*trapDescriptionTemplate)).Funcs(template.FuncMap{
-		"groupAlertsByLabel":  commons.GroupAlertsByLabel,
-		"groupAlertsByName":   commons.GroupAlertsByName,
-		"groupAlertsByStatus": commons.GroupAlertsByStatus,
-	}).ParseFiles(*trapDescriptionTemplate)
+
+	if configurationFileErr != nil {
+		return nil, nil, logger, configurationFileErr
+	}
+
+	// the problems are collected rather than returned, so that they are all reported at once
+	var errs []error
+
+	descriptionTemplate, err := commons.ParseTemplateFile(*trapDescriptionTemplate)
 	if err != nil {
-		return nil, logger, err
+		errs = append(errs, err)
+		descriptionTemplate = template.New(filepath.Base(*trapDescriptionTemplate))
+	}
+
+	var oidResolver *commons.OIDResolver
+	if *trapMIBDirectory != "" {
+		oidResolver, err = commons.LoadMIBs(*trapMIBDirectory)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to load MIB modules: %w", err))
+		}
+	}
+
+	if *trapDefaultOID, err = oidResolver.ResolveOID(*trapDefaultOID); err != nil {
+		errs = append(errs, fmt.Errorf("invalid default trap OID provided: %w", err))
+	}
+
+	if *trapResolutionDefaultOID != "" {
+		if *trapResolutionDefaultOID, err = oidResolver.ResolveOID(*trapResolutionDefaultOID); err != nil {
+			errs = append(errs, fmt.Errorf("invalid resolution trap OID provided: %w", err))
+		}
+	} else {
+		trapResolutionDefaultOID = nil
+	}
+
+	if *trapResolutionOIDLabel == "" {
+		trapResolutionOIDLabel = nil
+	}
+
+	if *trapDefaultObjectsBaseOID, err = oidResolver.ResolveOID(*trapDefaultObjectsBaseOID); err != nil {
+		errs = append(errs, fmt.Errorf("invalid default objects base OID provided: %w", err))
+	}
+
+	if *trapUserObjectsBaseOID, err = oidResolver.ResolveOID(*trapUserObjectsBaseOID); err != nil {
+		errs = append(errs, fmt.Errorf("invalid user objects base OID provided: %w", err))
+	}
+
+	if commandName == GenerateMIBCommand {
+		if *generateMIBModuleOID, err = oidResolver.ResolveOID(*generateMIBModuleOID); err != nil {
+			errs = append(errs, fmt.Errorf("invalid MIB module OID provided: %w", err))
+		}
 	}
 
 	minimumUserObjectSubOID := 0
@@ -123,28 +289,34 @@ func ParseConfiguration(args []string) (*SNMPNotifierConfiguration, *slog.Logger
 	}
 
 	userObjectsTemplates := make(map[int]template.Template)
+	userObjectsTypes := make(map[int]string)
 	if trapUserObject != nil {
 		for subOid, templatePath := range *trapUserObject {
+			objectType := trapsender.StringObjectType
+			if prefix, path, found := strings.Cut(templatePath, ":"); found && slices.Contains(trapsender.ObjectTypes, prefix) {
+				objectType, templatePath = prefix, path
+			}
+
 			oidValue, err := strconv.Atoi(subOid)
 			if err != nil || oidValue < minimumUserObjectSubOID {
-				return nil, logger, fmt.Errorf("invalid object ID: %s. Object ID must be a number greater or equal to 4", subOid)
+				errs = append(errs, fmt.Errorf("invalid object ID: %s. Object ID must be a number greater or equal to 4", subOid))
+				continue
 			}
 
 			_, defined := userObjectsTemplates[oidValue]
 			if defined {
-				return nil, logger, fmt.Errorf("invalid object ID: %d defined twice", oidValue)
+				errs = append(errs, fmt.Errorf("invalid object ID: %d defined twice", oidValue))
+				continue
 			}
 
-			currentTemplate, err := template.New(filepath.Base(templatePath)).Funcs(template.FuncMap{
-				"groupAlertsByLabel":  commons.GroupAlertsByLabel,
-				"groupAlertsByName":   commons.GroupAlertsByName,
-				"groupAlertsByStatus": commons.GroupAlertsByStatus,
-			}).ParseFiles(templatePath)
+			currentTemplate, err := commons.ParseTemplateFile(templatePath)
 			if err != nil {
-				return nil, logger, err
+				errs = append(errs, err)
+				continue
 			}
 
 			userObjectsTemplates[oidValue] = *currentTemplate
+			userObjectsTypes[oidValue] = objectType
 		}
 	}
 
@@ -159,35 +331,58 @@ func ParseConfiguration(args []string) (*SNMPNotifierConfiguration, *slog.Logger
 		contentTemplate := userObjectsTemplates[subOID]
 		userObject := trapsender.UserObject{
 			SubOID:          subOID,
+			Type:            userObjectsTypes[subOID],
 			ContentTemplate: contentTemplate,
 		}
+		if configurationFile != nil {
+			fileUserObject := configurationFile.TrapSender.UserObjects[subOID]
+			userObject.Name, userObject.Description = fileUserObject.Name, fileUserObject.Description
+		}
 		userObjects[index] = userObject
 	}
 
-	if !commons.IsOID(*trapDefaultOID) {
-		return nil, logger, fmt.Errorf("invalid default trap OID provided: %s", *trapDefaultOID)
-	}
-
-	if *trapResolutionDefaultOID != "" && !commons.IsOID(*trapResolutionDefaultOID) {
-		return nil, logger, fmt.Errorf("invalid resolution trap OID provided: %s", *trapResolutionDefaultOID)
-	} else if *trapResolutionDefaultOID == "" {
-		trapResolutionDefaultOID = nil
-	}
+	severities := strings.Split(*alertSeverities, ",")
 
-	if *trapResolutionOIDLabel == "" {
-		trapResolutionOIDLabel = nil
+	var severityLevels map[string]int
+	if len(*alertSeverityLevel) > 0 {
+		severityLevels = make(map[string]int)
+		for severity, level := range *alertSeverityLevel {
+			if !slices.Contains(severities, severity) {
+				errs = append(errs, fmt.Errorf("invalid severity level: unknown severity %s", severity))
+				continue
+			}
+			levelValue, err := strconv.ParseInt(level, 10, 32)
+			// the levels are the values of the SnmpNotifierSeverityLevel textual convention
+			if err != nil || levelValue < 1 {
+				errs = append(errs, fmt.Errorf("invalid level of severity %s: %s", severity, level))
+				continue
+			}
+			severityLevels[severity] = int(levelValue)
+		}
+		for _, severity := range severities {
+			if _, found := severityLevels[severity]; !found {
+				errs = append(errs, fmt.Errorf("missing level of severity %s", severity))
+			}
+		}
 	}
 
-	if !commons.IsOID(*trapDefaultObjectsBaseOID) {
-		return nil, logger, fmt.Errorf("invalid default objects base OID provided: %s", *trapDefaultObjectsBaseOID)
+	var renotifyIntervals map[string]time.Duration
+	if len(*alertRenotifyInterval) > 0 {
+		renotifyIntervals = make(map[string]time.Duration)
 	}
-
-	if !commons.IsOID(*trapUserObjectsBaseOID) {
-		return nil, logger, fmt.Errorf("invalid user objects base OID provided: %s", *trapUserObjectsBaseOID)
+	for severity, interval := range *alertRenotifyInterval {
+		if !slices.Contains(severities, severity) {
+			errs = append(errs, fmt.Errorf("invalid renotify interval: unknown severity %s", severity))
+			continue
+		}
+		intervalValue, err := time.ParseDuration(interval)
+		if err != nil || intervalValue <= 0 {
+			errs = append(errs, fmt.Errorf("invalid renotify interval of severity %s: %s", severity, interval))
+			continue
+		}
+		renotifyIntervals[severity] = intervalValue
 	}
 
-	severities := strings.Split(*alertSeverities, ",")
-
 	alertParserConfiguration := alertparser.Configuration{
 		TrapDefaultOID:            *trapDefaultOID,
 		TrapOIDLabel:              *trapOIDLabel,
@@ -195,23 +390,20 @@ func ParseConfiguration(args []string) (*SNMPNotifierConfiguration, *slog.Logger
 		TrapResolutionOIDLabel:    trapResolutionOIDLabel,
 		DefaultSeverity:           *alertDefaultSeverity,
 		Severities:                severities,
+		SeverityLevels:            severityLevels,
 		SeverityLabel:             *alertSeverityLabel,
 		TrapDefaultObjectsBaseOID: *trapDefaultObjectsBaseOID,
 		TrapUserObjectsBaseOID:    *trapUserObjectsBaseOID,
-	}
-
-	isV2c := *snmpVersion == "V2c"
-
-	snmpDestinations := []string{}
-	for _, destination := range *snmpDestination {
-		snmpDestinations = append(snmpDestinations, destination.String())
+		TrapGrouping:              *trapGrouping,
+		TrapAlertID:               *trapAlertID,
+		OIDResolver:               oidResolver,
 	}
 
 	var engineStartTime int
 	if *snmpEngineStartTime == "" {
 		bootTime, err := host.BootTime()
 		if err != nil {
-			return nil, logger, fmt.Errorf("unable to get the host boot time: %w", err)
+			errs = append(errs, fmt.Errorf("unable to get the host boot time: %w", err))
 		}
 		if bootTime > math.MaxInt {
 			bootTime = 0
@@ -220,59 +412,239 @@ func ParseConfiguration(args []string) (*SNMPNotifierConfiguration, *slog.Logger
 	} else {
 		engineStartTime, err = strconv.Atoi(*snmpEngineStartTime)
 		if err != nil {
-			return nil, logger, fmt.Errorf("unable to parse snmp engine start time: %w", err)
+			errs = append(errs, fmt.Errorf("unable to parse snmp engine start time: %w", err))
+		}
+	}
+
+	defaultDestination := trapsender.Destination{
+		Version:                *snmpVersion,
+		Retries:                *snmpRetries,
+		Timeout:                *snmpTimeout,
+		Inform:                 *snmpInform,
+		Community:              *snmpCommunity,
+		AgentAddress:           *snmpAgentAddress,
+		AuthenticationEnabled:  *snmpAuthenticationEnabled,
+		AuthenticationProtocol: *snmpAuthenticationProtocol,
+		AuthenticationUsername: *snmpAuthenticationUsername,
+		AuthenticationPassword: *snmpAuthenticationPassword,
+		PrivateEnabled:         *snmpPrivateEnabled,
+		PrivateProtocol:        *snmpPrivateProtocol,
+		PrivatePassword:        *snmpPrivatePassword,
+		SecurityEngineID:       *snmpSecurityEngineID,
+		ContextEngineID:        *snmpContextEngineID,
+		ContextName:            *snmpContextName,
+	}
+
+	snmpDestinations := []trapsender.Destination{}
+	if configurationFile != nil && len(configurationFile.TrapSender.SNMPDestinations) > 0 && !snmpDestinationSetByUser {
+		for _, fileDestination := range configurationFile.TrapSender.SNMPDestinations {
+			snmpDestinations = append(snmpDestinations, fileDestination.apply(defaultDestination))
+		}
+	} else {
+		for _, address := range *snmpDestination {
+			destination := defaultDestination
+			destination.Address = address.String()
+			snmpDestinations = append(snmpDestinations, destination)
 		}
 	}
 
+	destinationNames := make(map[string]bool)
+	for index, destination := range snmpDestinations {
+		if destination.Name == "" {
+			destination.Name = destination.Address
+		}
+		if destinationNames[destination.Name] {
+			errs = append(errs, fmt.Errorf("SNMP destination defined twice: %s", destination.Name))
+			continue
+		}
+		destinationNames[destination.Name] = true
+
+		snmpDestinations[index], err = checkDestination(destination)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	var (
+		routes              []trapsender.Route
+		defaultDestinations []string
+	)
+	if configurationFile != nil {
+		for _, fileRoute := range configurationFile.TrapSender.Routes {
+			route, err := fileRoute.toRoute(destinationNames)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			routes = append(routes, route)
+		}
+
+		for _, name := range configurationFile.TrapSender.DefaultDestinations {
+			if !destinationNames[name] {
+				errs = append(errs, fmt.Errorf("unknown default destination: %s", name))
+			}
+		}
+		defaultDestinations = configurationFile.TrapSender.DefaultDestinations
+	}
+
 	trapSenderConfiguration := trapsender.Configuration{
-		SNMPVersion:             *snmpVersion,
-		SNMPDestination:         snmpDestinations,
-		SNMPRetries:             *snmpRetries,
+		SNMPDestinations:        snmpDestinations,
+		Routes:                  routes,
+		DefaultDestinations:     defaultDestinations,
 		DescriptionTemplate:     *descriptionTemplate,
+		DescriptionType:         *trapDescriptionType,
 		UserObjects:             userObjects,
-		SNMPTimeout:             *snmpTimeout,
 		SNMPEngineStartTimeUnix: engineStartTime,
+		SendTimeout:             *snmpSendTimeout,
+		MaxVarBindSize:          int(*snmpMaxVarBindSize),
+		MaxPDUSize:              int(*snmpMaxPDUSize),
+		MaxAlertsPerTrap:        *trapMaxAlertsPerTrap,
+		DryRun:                  *snmpDryRun,
+		DryRunFile:              *snmpDryRunFile,
 	}
 
-	if isV2c {
-		trapSenderConfiguration.SNMPCommunity = *snmpCommunity
+	if *trapMaxAlertsPerTrap < 0 {
+		errs = append(errs, fmt.Errorf("invalid maximum number of alerts per trap: %d", *trapMaxAlertsPerTrap))
 	}
 
-	if !isV2c {
-		trapSenderConfiguration.SNMPAuthenticationUsername = *snmpAuthenticationUsername
-		trapSenderConfiguration.SNMPSecurityEngineID = *snmpSecurityEngineID
-		trapSenderConfiguration.SNMPContextEngineID = *snmpContextEngineID
-		trapSenderConfiguration.SNMPContextName = *snmpContextName
+	if *snmpQueueSize < 0 {
+		errs = append(errs, fmt.Errorf("invalid SNMP queue size: %d", *snmpQueueSize))
+	} else if *snmpQueueSize > 0 {
+		if *snmpQueueWorkers < 1 {
+			errs = append(errs, fmt.Errorf("invalid number of SNMP queue workers: %d", *snmpQueueWorkers))
+		}
+		if *snmpQueueRetryBackoff <= 0 || *snmpQueueMaxRetryBackoff < *snmpQueueRetryBackoff {
+			errs = append(errs, fmt.Errorf("invalid SNMP queue retry backoffs: %s, up to %s", *snmpQueueRetryBackoff, *snmpQueueMaxRetryBackoff))
+		}
+		trapSenderConfiguration.QueueSize = *snmpQueueSize
+		trapSenderConfiguration.QueueWorkers = *snmpQueueWorkers
+		trapSenderConfiguration.QueueRetries = *snmpQueueRetries
+		trapSenderConfiguration.QueueRetryBackoff = *snmpQueueRetryBackoff
+		trapSenderConfiguration.QueueMaxRetryBackoff = *snmpQueueMaxRetryBackoff
 	}
 
-	if isV2c && (*snmpAuthenticationEnabled || *snmpPrivateEnabled) {
-		return nil, logger, fmt.Errorf("SNMP authentication or private only available with SNMP v3")
+	if *snmpSpoolDirectory != "" {
+		if *snmpSpoolReplayInterval <= 0 {
+			errs = append(errs, fmt.Errorf("invalid SNMP spool replay interval: %s", *snmpSpoolReplayInterval))
+		}
+		trapSenderConfiguration.SpoolDirectory = *snmpSpoolDirectory
+		trapSenderConfiguration.SpoolMaxAge = *snmpSpoolMaxAge
+		trapSenderConfiguration.SpoolMaxSize = int64(*snmpSpoolMaxSize)
+		trapSenderConfiguration.SpoolReplayInterval = *snmpSpoolReplayInterval
 	}
 
-	if !*snmpAuthenticationEnabled && *snmpPrivateEnabled {
-		return nil, logger, fmt.Errorf("SNMP private encryption requires authentication enabled")
+	httpServerConfiguration := httpserver.Configuration{
+		ToolKitConfiguration: *toolKitConfiguration,
 	}
 
-	if *snmpAuthenticationEnabled {
-		trapSenderConfiguration.SNMPAuthenticationEnabled = *snmpAuthenticationEnabled
-		trapSenderConfiguration.SNMPAuthenticationProtocol = *snmpAuthenticationProtocol
-		trapSenderConfiguration.SNMPAuthenticationPassword = *snmpAuthenticationPassword
+	alertStoreConfiguration := alertstore.Configuration{
+		RenotifyIntervals: renotifyIntervals,
+		StateFile:         *alertStateFile,
+		MaxAge:            *alertMaxAge,
 	}
-	if *snmpPrivateEnabled {
-		trapSenderConfiguration.SNMPPrivateEnabled = *snmpPrivateEnabled
-		trapSenderConfiguration.SNMPPrivateProtocol = *snmpPrivateProtocol
-		trapSenderConfiguration.SNMPPrivatePassword = *snmpPrivatePassword
+	if *alertMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("invalid alert maximum age: %s", *alertMaxAge))
 	}
 
-	httpServerConfiguration := httpserver.Configuration{
-		ToolKitConfiguration: *toolKitConfiguration,
+	heartbeatConfiguration := heartbeat.Configuration{}
+	if *heartbeatInterval < 0 {
+		errs = append(errs, fmt.Errorf("invalid heartbeat interval: %s", *heartbeatInterval))
+	} else if *heartbeatInterval > 0 {
+		heartbeatConfiguration.Interval = *heartbeatInterval
+		if heartbeatConfiguration.TrapOID, err = oidResolver.ResolveOID(*heartbeatOID); err != nil {
+			errs = append(errs, fmt.Errorf("invalid heartbeat trap OID provided: %w", err))
+		}
+	}
+
+	agentConfiguration := snmpagent.Configuration{}
+	if *agentListenAddress != "" {
+		agentConfiguration.ListenAddress = *agentListenAddress
+		agentConfiguration.Community = *agentCommunity
+		alertStoreConfiguration.KeepAlerts = true
 	}
 
 	configuration := SNMPNotifierConfiguration{
 		AlertParserConfiguration: alertParserConfiguration,
 		TrapSenderConfiguration:  trapSenderConfiguration,
 		HTTPServerConfiguration:  httpServerConfiguration,
+		AlertStoreConfiguration:  alertStoreConfiguration,
+		AgentConfiguration:       agentConfiguration,
+		HeartbeatConfiguration:   heartbeatConfiguration,
+	}
+
+	command := Command{
+		Name:          commandName,
+		MIBModuleName: *generateMIBModuleName,
+		MIBModuleOID:  *generateMIBModuleOID,
+		MIBOutput:     *generateMIBOutput,
+		AlertsFile:    *renderAlertsFile,
+		RenderFormat:  *renderFormat,
+	}
+
+	if len(errs) > 0 {
+		return &command, nil, logger, errors.Join(errs...)
+	}
+	return &command, &configuration, logger, nil
+}
+
+// checkDestination validates the settings of a destination, and drops those not relevant to its SNMP version
+func checkDestination(destination trapsender.Destination) (trapsender.Destination, error) {
+	if destination.Address == "" {
+		return destination, fmt.Errorf("SNMP destination address is missing")
+	}
+
+	if commons.IndexOf(destination.Version, snmpVersions) == -1 {
+		return destination, fmt.Errorf("invalid SNMP version for destination %s: %s", destination.Address, destination.Version)
+	}
+
+	isV3 := destination.Version == "V3"
+
+	if !isV3 && (destination.AuthenticationEnabled || destination.PrivateEnabled) {
+		return destination, fmt.Errorf("SNMP authentication or private only available with SNMP v3")
+	}
+
+	if destination.Version == "V1" && destination.Inform {
+		return destination, fmt.Errorf("SNMP informs not available with SNMP v1")
+	}
+
+	if destination.Version == "V1" && net.ParseIP(destination.AgentAddress).To4() == nil {
+		return destination, fmt.Errorf("invalid SNMP agent address for destination %s: %s", destination.Address, destination.AgentAddress)
+	}
+
+	if !destination.AuthenticationEnabled && destination.PrivateEnabled {
+		return destination, fmt.Errorf("SNMP private encryption requires authentication enabled")
+	}
+
+	if destination.AuthenticationEnabled && commons.IndexOf(destination.AuthenticationProtocol, snmpAuthenticationProtocols) == -1 {
+		return destination, fmt.Errorf("invalid SNMP authentication protocol for destination %s: %s", destination.Address, destination.AuthenticationProtocol)
+	}
+
+	if destination.PrivateEnabled && commons.IndexOf(destination.PrivateProtocol, snmpPrivateProtocols) == -1 {
+		return destination, fmt.Errorf("invalid SNMP private protocol for destination %s: %s", destination.Address, destination.PrivateProtocol)
+	}
+
+	if !isV3 {
+		destination.AuthenticationUsername = ""
+		destination.SecurityEngineID = ""
+		destination.ContextEngineID = ""
+		destination.ContextName = ""
+	} else {
+		destination.Community = ""
+	}
+
+	if destination.Version != "V1" {
+		destination.AgentAddress = ""
+	}
+
+	if !destination.AuthenticationEnabled {
+		destination.AuthenticationProtocol = ""
+		destination.AuthenticationPassword = ""
+	}
+
+	if !destination.PrivateEnabled {
+		destination.PrivateProtocol = ""
+		destination.PrivatePassword = ""
 	}
 
-	return &configuration, logger, err
+	return destination, nil
 }
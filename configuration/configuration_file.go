@@ -0,0 +1,432 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configuration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"go.yaml.in/yaml/v2"
+
+	"github.com/maxwo/snmp_notifier/mib"
+	"github.com/maxwo/snmp_notifier/trapsender"
+)
+
+const configurationFileFlag = "config.file"
+
+// fileConfiguration describes the YAML configuration file, section by section
+type fileConfiguration struct {
+	AlertParser alertParserFileConfiguration `yaml:"alert_parser"`
+	TrapSender  trapSenderFileConfiguration  `yaml:"trap_sender"`
+	HTTPServer  httpServerFileConfiguration  `yaml:"http_server"`
+	Agent       agentFileConfiguration       `yaml:"agent"`
+	Heartbeat   heartbeatFileConfiguration   `yaml:"heartbeat"`
+}
+
+type alertParserFileConfiguration struct {
+	Severities                []string                 `yaml:"severities"`
+	SeverityLevels            map[string]int           `yaml:"severity_levels"`
+	SeverityLabel             *string                  `yaml:"severity_label"`
+	DefaultSeverity           *string                  `yaml:"default_severity"`
+	TrapMIBDirectory          *string                  `yaml:"trap_mib_directory"`
+	TrapDefaultOID            *string                  `yaml:"trap_default_oid"`
+	TrapOIDLabel              *string                  `yaml:"trap_oid_label"`
+	TrapResolutionDefaultOID  *string                  `yaml:"trap_resolution_default_oid"`
+	TrapResolutionOIDLabel    *string                  `yaml:"trap_resolution_oid_label"`
+	TrapDefaultObjectsBaseOID *string                  `yaml:"trap_default_objects_base_oid"`
+	TrapUserObjectsBaseOID    *string                  `yaml:"trap_user_objects_base_oid"`
+	TrapGrouping              *string                  `yaml:"trap_grouping"`
+	TrapAlertID               *string                  `yaml:"trap_alert_id"`
+	RenotifyIntervals         map[string]time.Duration `yaml:"renotify_intervals"`
+	StateFile                 *string                  `yaml:"state_file"`
+	MaxAge                    *time.Duration           `yaml:"max_age"`
+}
+
+type trapSenderFileConfiguration struct {
+	SNMPDestinations        []destinationFileConfiguration `yaml:"snmp_destinations"`
+	SNMPRetries             *uint                          `yaml:"snmp_retries"`
+	SNMPVersion             *string                        `yaml:"snmp_version"`
+	SNMPTimeout             *time.Duration                 `yaml:"snmp_timeout"`
+	SNMPInform              *bool                          `yaml:"snmp_inform"`
+	SNMPEngineStartTimeUnix *int                           `yaml:"snmp_engine_start_time"`
+	SNMPSendTimeout         *time.Duration                 `yaml:"snmp_send_timeout"`
+	SNMPMaxVarBindSize      *string                        `yaml:"snmp_max_varbind_size"`
+	SNMPMaxPDUSize          *string                        `yaml:"snmp_max_pdu_size"`
+	SNMPDryRun              *bool                          `yaml:"snmp_dry_run"`
+	SNMPDryRunFile          *string                        `yaml:"snmp_dry_run_file"`
+
+	SNMPQueueSize            *int           `yaml:"snmp_queue_size"`
+	SNMPQueueWorkers         *int           `yaml:"snmp_queue_workers"`
+	SNMPQueueRetries         *uint          `yaml:"snmp_queue_retries"`
+	SNMPQueueRetryBackoff    *time.Duration `yaml:"snmp_queue_retry_backoff"`
+	SNMPQueueMaxRetryBackoff *time.Duration `yaml:"snmp_queue_max_retry_backoff"`
+
+	SNMPSpoolDirectory      *string        `yaml:"snmp_spool_directory"`
+	SNMPSpoolMaxAge         *time.Duration `yaml:"snmp_spool_max_age"`
+	SNMPSpoolMaxSize        *string        `yaml:"snmp_spool_max_size"`
+	SNMPSpoolReplayInterval *time.Duration `yaml:"snmp_spool_replay_interval"`
+
+	SNMPCommunity    *string `yaml:"snmp_community"`
+	SNMPAgentAddress *string `yaml:"snmp_agent_address"`
+
+	SNMPAuthenticationEnabled  *bool   `yaml:"snmp_authentication_enabled"`
+	SNMPAuthenticationProtocol *string `yaml:"snmp_authentication_protocol"`
+	SNMPAuthenticationUsername *string `yaml:"snmp_authentication_username"`
+	SNMPAuthenticationPassword *string `yaml:"snmp_authentication_password"`
+	SNMPPrivateEnabled         *bool   `yaml:"snmp_private_enabled"`
+	SNMPPrivateProtocol        *string `yaml:"snmp_private_protocol"`
+	SNMPPrivatePassword        *string `yaml:"snmp_private_password"`
+	SNMPSecurityEngineID       *string `yaml:"snmp_security_engine_id"`
+	SNMPContextEngineID        *string `yaml:"snmp_context_engine_id"`
+	SNMPContextName            *string `yaml:"snmp_context_name"`
+
+	Routes              []routeFileConfiguration `yaml:"routes"`
+	DefaultDestinations []string                 `yaml:"default_destinations"`
+
+	DescriptionTemplate *string                             `yaml:"description_template"`
+	DescriptionType     *string                             `yaml:"description_type"`
+	UserObjects         map[int]userObjectFileConfiguration `yaml:"user_objects"`
+	MaxAlertsPerTrap    *int                                `yaml:"max_alerts_per_trap"`
+}
+
+// userObjectFileConfiguration describes a user object, either as a template path or with its SNMP type,
+// and its name and description in the generated MIB module
+type userObjectFileConfiguration struct {
+	Template    string `yaml:"template"`
+	Type        string `yaml:"type"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+// destinationFileConfiguration describes a destination, either as a simple address or with its own settings.
+// Settings that are not defined are inherited from the trap sender settings.
+type destinationFileConfiguration struct {
+	Name    string         `yaml:"name"`
+	Address string         `yaml:"address"`
+	Version *string        `yaml:"version"`
+	Retries *uint          `yaml:"retries"`
+	Timeout *time.Duration `yaml:"timeout"`
+	Inform  *bool          `yaml:"inform"`
+
+	Community    *string `yaml:"community"`
+	AgentAddress *string `yaml:"agent_address"`
+
+	AuthenticationEnabled  *bool   `yaml:"authentication_enabled"`
+	AuthenticationProtocol *string `yaml:"authentication_protocol"`
+	AuthenticationUsername *string `yaml:"authentication_username"`
+	AuthenticationPassword *string `yaml:"authentication_password"`
+	PrivateEnabled         *bool   `yaml:"private_enabled"`
+	PrivateProtocol        *string `yaml:"private_protocol"`
+	PrivatePassword        *string `yaml:"private_password"`
+	SecurityEngineID       *string `yaml:"security_engine_id"`
+	ContextEngineID        *string `yaml:"context_engine_id"`
+	ContextName            *string `yaml:"context_name"`
+}
+
+// routeFileConfiguration describes the destinations of the alert groups matching a receiver and label matchers
+type routeFileConfiguration struct {
+	Receiver     string   `yaml:"receiver"`
+	Matchers     []string `yaml:"matchers"`
+	Destinations []string `yaml:"destinations"`
+}
+
+type httpServerFileConfiguration struct {
+	WebListenAddresses []string `yaml:"web_listen_addresses"`
+	WebSystemdSocket   *bool    `yaml:"web_systemd_socket"`
+	WebConfigFile      *string  `yaml:"web_config_file"`
+}
+
+type agentFileConfiguration struct {
+	ListenAddress *string `yaml:"listen_address"`
+	Community     *string `yaml:"community"`
+}
+
+type heartbeatFileConfiguration struct {
+	Interval *time.Duration `yaml:"interval"`
+	OID      *string        `yaml:"oid"`
+}
+
+// getConfigurationFileName looks for the configuration file in the command line, before it is parsed
+func getConfigurationFileName(args []string) string {
+	for index, arg := range args {
+		if value, found := strings.CutPrefix(arg, "--"+configurationFileFlag+"="); found {
+			return value
+		}
+		if arg == "--"+configurationFileFlag && index+1 < len(args) {
+			return args[index+1]
+		}
+	}
+	return ""
+}
+
+func loadConfigurationFile(fileName string) (*fileConfiguration, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read configuration file: %w", err)
+	}
+
+	configuration := fileConfiguration{}
+	if err := yaml.UnmarshalStrict(content, &configuration); err != nil {
+		return nil, fmt.Errorf("unable to parse configuration file %s: %w", fileName, err)
+	}
+	configuration.resolvePaths(filepath.Dir(fileName))
+
+	return &configuration, nil
+}
+
+// resolvePaths makes the relative paths of the configuration file relative to its directory, so that the file
+// does not depend on the working directory
+func (configuration *fileConfiguration) resolvePaths(directory string) {
+	resolvePath(directory, configuration.AlertParser.StateFile)
+	resolvePath(directory, configuration.AlertParser.TrapMIBDirectory)
+	resolvePath(directory, configuration.TrapSender.SNMPDryRunFile)
+	resolvePath(directory, configuration.TrapSender.SNMPSpoolDirectory)
+	resolvePath(directory, configuration.TrapSender.DescriptionTemplate)
+	for subOID, userObject := range configuration.TrapSender.UserObjects {
+		resolvePath(directory, &userObject.Template)
+		configuration.TrapSender.UserObjects[subOID] = userObject
+	}
+	resolvePath(directory, configuration.HTTPServer.WebConfigFile)
+}
+
+func resolvePath(directory string, path *string) {
+	if path != nil && *path != "" && !filepath.IsAbs(*path) {
+		*path = filepath.Join(directory, *path)
+	}
+}
+
+// flagDefaults returns the values of the configuration file, indexed by the flag they replace the default of
+func (configuration fileConfiguration) flagDefaults() map[string][]string {
+	defaults := map[string][]string{}
+
+	alertParser := configuration.AlertParser
+	if len(alertParser.Severities) > 0 {
+		defaults["alert.severities"] = []string{strings.Join(alertParser.Severities, ",")}
+	}
+	for severity, level := range alertParser.SeverityLevels {
+		defaults["alert.severity-level"] = append(defaults["alert.severity-level"], fmt.Sprintf("%s=%d", severity, level))
+	}
+	addStringDefault(defaults, "alert.severity-label", alertParser.SeverityLabel)
+	addStringDefault(defaults, "alert.default-severity", alertParser.DefaultSeverity)
+	for severity, interval := range alertParser.RenotifyIntervals {
+		defaults["alert.renotify-interval"] = append(defaults["alert.renotify-interval"], fmt.Sprintf("%s=%s", severity, interval))
+	}
+	addStringDefault(defaults, "alert.state-file", alertParser.StateFile)
+	if alertParser.MaxAge != nil {
+		defaults["alert.max-age"] = []string{alertParser.MaxAge.String()}
+	}
+	addStringDefault(defaults, "trap.mib-directory", alertParser.TrapMIBDirectory)
+	addStringDefault(defaults, "trap.default-oid", alertParser.TrapDefaultOID)
+	addStringDefault(defaults, "trap.oid-label", alertParser.TrapOIDLabel)
+	addStringDefault(defaults, "trap.resolution-default-oid", alertParser.TrapResolutionDefaultOID)
+	addStringDefault(defaults, "trap.resolution-oid-label", alertParser.TrapResolutionOIDLabel)
+	addStringDefault(defaults, "trap.default-objects-base-oid", alertParser.TrapDefaultObjectsBaseOID)
+	addStringDefault(defaults, "trap.user-objects-base-oid", alertParser.TrapUserObjectsBaseOID)
+	addStringDefault(defaults, "trap.grouping", alertParser.TrapGrouping)
+	addStringDefault(defaults, "trap.alert-id", alertParser.TrapAlertID)
+
+	trapSender := configuration.TrapSender
+	if trapSender.SNMPRetries != nil {
+		defaults["snmp.retries"] = []string{strconv.FormatUint(uint64(*trapSender.SNMPRetries), 10)}
+	}
+	addStringDefault(defaults, "snmp.version", trapSender.SNMPVersion)
+	if trapSender.SNMPTimeout != nil {
+		defaults["snmp.timeout"] = []string{trapSender.SNMPTimeout.String()}
+	}
+	addBoolDefault(defaults, "snmp.inform", trapSender.SNMPInform)
+	if trapSender.SNMPEngineStartTimeUnix != nil {
+		defaults["snmp.engine-start-time"] = []string{strconv.Itoa(*trapSender.SNMPEngineStartTimeUnix)}
+	}
+	if trapSender.SNMPSendTimeout != nil {
+		defaults["snmp.send-timeout"] = []string{trapSender.SNMPSendTimeout.String()}
+	}
+	addStringDefault(defaults, "snmp.max-varbind-size", trapSender.SNMPMaxVarBindSize)
+	addStringDefault(defaults, "snmp.max-pdu-size", trapSender.SNMPMaxPDUSize)
+	addBoolDefault(defaults, "snmp.dry-run", trapSender.SNMPDryRun)
+	addStringDefault(defaults, "snmp.dry-run-file", trapSender.SNMPDryRunFile)
+	if trapSender.SNMPQueueSize != nil {
+		defaults["snmp.queue-size"] = []string{strconv.Itoa(*trapSender.SNMPQueueSize)}
+	}
+	if trapSender.SNMPQueueWorkers != nil {
+		defaults["snmp.queue-workers"] = []string{strconv.Itoa(*trapSender.SNMPQueueWorkers)}
+	}
+	if trapSender.SNMPQueueRetries != nil {
+		defaults["snmp.queue-retries"] = []string{strconv.FormatUint(uint64(*trapSender.SNMPQueueRetries), 10)}
+	}
+	if trapSender.SNMPQueueRetryBackoff != nil {
+		defaults["snmp.queue-retry-backoff"] = []string{trapSender.SNMPQueueRetryBackoff.String()}
+	}
+	if trapSender.SNMPQueueMaxRetryBackoff != nil {
+		defaults["snmp.queue-max-retry-backoff"] = []string{trapSender.SNMPQueueMaxRetryBackoff.String()}
+	}
+	addStringDefault(defaults, "snmp.spool-directory", trapSender.SNMPSpoolDirectory)
+	if trapSender.SNMPSpoolMaxAge != nil {
+		defaults["snmp.spool-max-age"] = []string{trapSender.SNMPSpoolMaxAge.String()}
+	}
+	addStringDefault(defaults, "snmp.spool-max-size", trapSender.SNMPSpoolMaxSize)
+	if trapSender.SNMPSpoolReplayInterval != nil {
+		defaults["snmp.spool-replay-interval"] = []string{trapSender.SNMPSpoolReplayInterval.String()}
+	}
+	addStringDefault(defaults, "snmp.community", trapSender.SNMPCommunity)
+	addStringDefault(defaults, "snmp.agent-address", trapSender.SNMPAgentAddress)
+	addBoolDefault(defaults, "snmp.authentication-enabled", trapSender.SNMPAuthenticationEnabled)
+	addStringDefault(defaults, "snmp.authentication-protocol", trapSender.SNMPAuthenticationProtocol)
+	addStringDefault(defaults, "snmp.authentication-username", trapSender.SNMPAuthenticationUsername)
+	addStringDefault(defaults, "snmp.authentication-password", trapSender.SNMPAuthenticationPassword)
+	addBoolDefault(defaults, "snmp.private-enabled", trapSender.SNMPPrivateEnabled)
+	addStringDefault(defaults, "snmp.private-protocol", trapSender.SNMPPrivateProtocol)
+	addStringDefault(defaults, "snmp.private-password", trapSender.SNMPPrivatePassword)
+	addStringDefault(defaults, "snmp.security-engine-id", trapSender.SNMPSecurityEngineID)
+	addStringDefault(defaults, "snmp.context-engine-id", trapSender.SNMPContextEngineID)
+	addStringDefault(defaults, "snmp.context-name", trapSender.SNMPContextName)
+	addStringDefault(defaults, "trap.description-template", trapSender.DescriptionTemplate)
+	addStringDefault(defaults, "trap.description-type", trapSender.DescriptionType)
+	if trapSender.MaxAlertsPerTrap != nil {
+		defaults["trap.max-alerts-per-trap"] = []string{strconv.Itoa(*trapSender.MaxAlertsPerTrap)}
+	}
+	for subOID, userObject := range trapSender.UserObjects {
+		templatePath := userObject.Template
+		if userObject.Type != "" {
+			templatePath = userObject.Type + ":" + templatePath
+		}
+		defaults["trap.user-object"] = append(defaults["trap.user-object"], fmt.Sprintf("%d=%s", subOID, templatePath))
+	}
+
+	httpServer := configuration.HTTPServer
+	if len(httpServer.WebListenAddresses) > 0 {
+		defaults["web.listen-address"] = httpServer.WebListenAddresses
+	}
+	addBoolDefault(defaults, "web.systemd-socket", httpServer.WebSystemdSocket)
+	addStringDefault(defaults, "web.config.file", httpServer.WebConfigFile)
+
+	agent := configuration.Agent
+	addStringDefault(defaults, "agent.listen-address", agent.ListenAddress)
+	addStringDefault(defaults, "agent.community", agent.Community)
+
+	heartbeat := configuration.Heartbeat
+	if heartbeat.Interval != nil {
+		defaults["heartbeat.interval"] = []string{heartbeat.Interval.String()}
+	}
+	addStringDefault(defaults, "heartbeat.oid", heartbeat.OID)
+
+	return defaults
+}
+
+// UnmarshalYAML accepts either an address or a destination with its own settings
+func (destination *destinationFileConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var address string
+	if err := unmarshal(&address); err == nil {
+		destination.Address = address
+		return nil
+	}
+
+	type plain destinationFileConfiguration
+	return unmarshal((*plain)(destination))
+}
+
+// UnmarshalYAML accepts either a template path or a user object with its SNMP type
+func (userObject *userObjectFileConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var templatePath string
+	if err := unmarshal(&templatePath); err == nil {
+		userObject.Template = templatePath
+		return nil
+	}
+
+	type plain userObjectFileConfiguration
+	if err := unmarshal((*plain)(userObject)); err != nil {
+		return err
+	}
+	if userObject.Type != "" && !slices.Contains(trapsender.ObjectTypes, userObject.Type) {
+		return fmt.Errorf("invalid user object type: %s", userObject.Type)
+	}
+	if userObject.Name != "" && !mib.IsDescriptor(userObject.Name) {
+		return fmt.Errorf("invalid user object name: %s", userObject.Name)
+	}
+	return nil
+}
+
+// apply overrides the settings of the given destination with those defined in the configuration file
+func (destination destinationFileConfiguration) apply(defaultDestination trapsender.Destination) trapsender.Destination {
+	result := defaultDestination
+	result.Name = destination.Name
+	result.Address = destination.Address
+	setIfDefined(&result.Version, destination.Version)
+	setIfDefined(&result.Retries, destination.Retries)
+	setIfDefined(&result.Timeout, destination.Timeout)
+	setIfDefined(&result.Inform, destination.Inform)
+	setIfDefined(&result.Community, destination.Community)
+	setIfDefined(&result.AgentAddress, destination.AgentAddress)
+	setIfDefined(&result.AuthenticationEnabled, destination.AuthenticationEnabled)
+	setIfDefined(&result.AuthenticationProtocol, destination.AuthenticationProtocol)
+	setIfDefined(&result.AuthenticationUsername, destination.AuthenticationUsername)
+	setIfDefined(&result.AuthenticationPassword, destination.AuthenticationPassword)
+	setIfDefined(&result.PrivateEnabled, destination.PrivateEnabled)
+	setIfDefined(&result.PrivateProtocol, destination.PrivateProtocol)
+	setIfDefined(&result.PrivatePassword, destination.PrivatePassword)
+	setIfDefined(&result.SecurityEngineID, destination.SecurityEngineID)
+	setIfDefined(&result.ContextEngineID, destination.ContextEngineID)
+	setIfDefined(&result.ContextName, destination.ContextName)
+	return result
+}
+
+// toRoute checks the route destinations and parses its matchers
+func (route routeFileConfiguration) toRoute(destinationNames map[string]bool) (trapsender.Route, error) {
+	if len(route.Destinations) == 0 {
+		return trapsender.Route{}, fmt.Errorf("route without destination")
+	}
+	for _, name := range route.Destinations {
+		if !destinationNames[name] {
+			return trapsender.Route{}, fmt.Errorf("unknown destination in route: %s", name)
+		}
+	}
+
+	matchers := labels.Matchers{}
+	for _, matcher := range route.Matchers {
+		parsedMatcher, err := labels.ParseMatcher(matcher)
+		if err != nil {
+			return trapsender.Route{}, fmt.Errorf("invalid route matcher %s: %w", matcher, err)
+		}
+		matchers = append(matchers, parsedMatcher)
+	}
+
+	return trapsender.Route{
+		Receiver:     route.Receiver,
+		Matchers:     matchers,
+		Destinations: route.Destinations,
+	}, nil
+}
+
+func setIfDefined[T any](target *T, value *T) {
+	if value != nil {
+		*target = *value
+	}
+}
+
+func addStringDefault(defaults map[string][]string, flag string, value *string) {
+	if value != nil {
+		defaults[flag] = []string{*value}
+	}
+}
+
+func addBoolDefault(defaults map[string][]string, flag string, value *bool) {
+	if value != nil {
+		defaults[flag] = []string{strconv.FormatBool(*value)}
+	}
+}
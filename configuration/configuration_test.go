@@ -16,6 +16,7 @@ package configuration
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -24,12 +25,16 @@ import (
 	"time"
 
 	"github.com/maxwo/snmp_notifier/alertparser"
+	"github.com/maxwo/snmp_notifier/alertstore"
 	"github.com/maxwo/snmp_notifier/commons"
+	"github.com/maxwo/snmp_notifier/heartbeat"
 	"github.com/maxwo/snmp_notifier/httpserver"
+	"github.com/maxwo/snmp_notifier/snmpagent"
 	"github.com/maxwo/snmp_notifier/trapsender"
 	"github.com/prometheus/exporter-toolkit/web"
 
 	"github.com/go-test/deep"
+	"github.com/prometheus/alertmanager/pkg/labels"
 )
 
 var falseValue = false
@@ -48,14 +53,23 @@ func TestDefaultConfiguration(t *testing.T) {
 				Severities:                []string{"critical", "warning", "info"},
 				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
 				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
 			},
 			trapsender.Configuration{
-				SNMPVersion:     "V2c",
-				SNMPDestination: []string{"127.0.0.1:162"},
-				SNMPRetries:     1,
-				SNMPTimeout:     5 * time.Second,
-				SNMPCommunity:   "public",
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.1:162",
+						Address:   "127.0.0.1:162",
+						Version:   "V2c",
+						Retries:   1,
+						Timeout:   5 * time.Second,
+						Community: "public",
+					},
+				},
 				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
 			},
 			httpserver.Configuration{
 				ToolKitConfiguration: web.FlagConfig{
@@ -64,6 +78,9 @@ func TestDefaultConfiguration(t *testing.T) {
 					WebListenAddresses: &testListenAddresses,
 				},
 			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
 		},
 		true,
 	)
@@ -81,14 +98,69 @@ func TestSimpleConfiguration(t *testing.T) {
 				Severities:                []string{"critical", "warning", "info"},
 				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
 				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
+			},
+			trapsender.Configuration{
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.1:162",
+						Address:   "127.0.0.1:162",
+						Version:   "V2c",
+						Retries:   1,
+						Timeout:   10 * time.Second,
+						Community: "public",
+					},
+				},
+				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
+			},
+			httpserver.Configuration{
+				ToolKitConfiguration: web.FlagConfig{
+					WebSystemdSocket:   &falseValue,
+					WebConfigFile:      &emptyString,
+					WebListenAddresses: &testListenAddresses,
+				},
+			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
+		},
+		true,
+	)
+}
+
+func TestSeverityLevelsConfiguration(t *testing.T) {
+	expectConfigurationFromCommandLine(t,
+		"--web.listen-address=:1234 --trap.description-template=../description-template.tpl --alert.severities=critical,major,minor --alert.severity-level=critical=5 --alert.severity-level=major=4 --alert.severity-level=minor=2",
+		SNMPNotifierConfiguration{
+			alertparser.Configuration{
+				TrapDefaultOID:            "1.3.6.1.4.1.98789.1",
+				TrapOIDLabel:              "oid",
+				DefaultSeverity:           "critical",
+				SeverityLabel:             "severity",
+				Severities:                []string{"critical", "major", "minor"},
+				SeverityLevels:            map[string]int{"critical": 5, "major": 4, "minor": 2},
+				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
+				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
 			},
 			trapsender.Configuration{
-				SNMPVersion:     "V2c",
-				SNMPDestination: []string{"127.0.0.1:162"},
-				SNMPRetries:     1,
-				SNMPTimeout:     10 * time.Second,
-				SNMPCommunity:   "public",
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.1:162",
+						Address:   "127.0.0.1:162",
+						Version:   "V2c",
+						Retries:   1,
+						Timeout:   5 * time.Second,
+						Community: "public",
+					},
+				},
 				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
 			},
 			httpserver.Configuration{
 				ToolKitConfiguration: web.FlagConfig{
@@ -97,6 +169,9 @@ func TestSimpleConfiguration(t *testing.T) {
 					WebListenAddresses: &testListenAddresses,
 				},
 			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
 		},
 		true,
 	)
@@ -105,7 +180,7 @@ func TestSimpleConfiguration(t *testing.T) {
 func TestV2Configuration(t *testing.T) {
 	expectConfigurationFromCommandLineAndEnvironmentVariables(
 		t,
-		"--web.listen-address=:1234 --trap.description-template=../description-template.tpl --snmp.destination=127.0.0.2:163 --snmp.retries=4 --trap.default-oid=4.4.4 --trap.oid-label=other-oid --alert.default-severity=warning --alert.severity-label=severity --alert.severities=critical,error,warning,info",
+		"--web.listen-address=:1234 --trap.description-template=../description-template.tpl --snmp.destination=127.0.0.2:163 --snmp.retries=4 --snmp.inform --trap.default-oid=4.4.4 --trap.oid-label=other-oid --alert.default-severity=warning --alert.severity-label=severity --alert.severities=critical,error,warning,info",
 		map[string]string{
 			"SNMP_NOTIFIER_COMMUNITY": "private",
 		},
@@ -118,14 +193,172 @@ func TestV2Configuration(t *testing.T) {
 				Severities:                []string{"critical", "error", "warning", "info"},
 				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
 				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
+			},
+			trapsender.Configuration{
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.2:163",
+						Address:   "127.0.0.2:163",
+						Version:   "V2c",
+						Retries:   4,
+						Timeout:   5 * time.Second,
+						Inform:    true,
+						Community: "private",
+					},
+				},
+				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
+			},
+			httpserver.Configuration{
+				ToolKitConfiguration: web.FlagConfig{
+					WebSystemdSocket:   &falseValue,
+					WebConfigFile:      &emptyString,
+					WebListenAddresses: &testListenAddresses,
+				},
+			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
+		},
+		true,
+	)
+}
+
+func TestV1Configuration(t *testing.T) {
+	expectConfigurationFromCommandLine(
+		t,
+		"--web.listen-address=:1234 --trap.description-template=../description-template.tpl --snmp.version=V1 --snmp.destination=127.0.0.2:163 --snmp.agent-address=10.0.0.1",
+		SNMPNotifierConfiguration{
+			alertparser.Configuration{
+				TrapDefaultOID:            "1.3.6.1.4.1.98789.1",
+				TrapOIDLabel:              "oid",
+				DefaultSeverity:           "critical",
+				SeverityLabel:             "severity",
+				Severities:                []string{"critical", "warning", "info"},
+				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
+				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
 			},
 			trapsender.Configuration{
-				SNMPVersion:     "V2c",
-				SNMPDestination: []string{"127.0.0.2:163"},
-				SNMPRetries:     4,
-				SNMPTimeout:     5 * time.Second,
-				SNMPCommunity:   "private",
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:         "127.0.0.2:163",
+						Address:      "127.0.0.2:163",
+						Version:      "V1",
+						Retries:      1,
+						Timeout:      5 * time.Second,
+						Community:    "public",
+						AgentAddress: "10.0.0.1",
+					},
+				},
 				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
+			},
+			httpserver.Configuration{
+				ToolKitConfiguration: web.FlagConfig{
+					WebSystemdSocket:   &falseValue,
+					WebConfigFile:      &emptyString,
+					WebListenAddresses: &testListenAddresses,
+				},
+			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
+		},
+		true,
+	)
+}
+
+func TestQueueConfiguration(t *testing.T) {
+	expectConfigurationFromCommandLine(
+		t,
+		"--web.listen-address=:1234 --trap.description-template=../description-template.tpl --snmp.destination=127.0.0.2:163 --snmp.queue-size=100 --snmp.queue-workers=4 --snmp.queue-retry-backoff=2s --snmp.queue-max-retry-backoff=30s",
+		SNMPNotifierConfiguration{
+			alertparser.Configuration{
+				TrapDefaultOID:            "1.3.6.1.4.1.98789.1",
+				TrapOIDLabel:              "oid",
+				DefaultSeverity:           "critical",
+				SeverityLabel:             "severity",
+				Severities:                []string{"critical", "warning", "info"},
+				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
+				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
+			},
+			trapsender.Configuration{
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.2:163",
+						Address:   "127.0.0.2:163",
+						Version:   "V2c",
+						Retries:   1,
+						Timeout:   5 * time.Second,
+						Community: "public",
+					},
+				},
+				UserObjects:          make([]trapsender.UserObject, 0),
+				DescriptionType:      "string",
+				SendTimeout:          30 * time.Second,
+				QueueSize:            100,
+				QueueWorkers:         4,
+				QueueRetries:         3,
+				QueueRetryBackoff:    2 * time.Second,
+				QueueMaxRetryBackoff: 30 * time.Second,
+			},
+			httpserver.Configuration{
+				ToolKitConfiguration: web.FlagConfig{
+					WebSystemdSocket:   &falseValue,
+					WebConfigFile:      &emptyString,
+					WebListenAddresses: &testListenAddresses,
+				},
+			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
+		},
+		true,
+	)
+}
+
+func TestSpoolConfiguration(t *testing.T) {
+	expectConfigurationFromCommandLine(
+		t,
+		"--web.listen-address=:1234 --trap.description-template=../description-template.tpl --snmp.destination=127.0.0.2:163 --snmp.spool-directory=/var/spool/snmp_notifier --snmp.spool-max-size=1MiB --snmp.spool-replay-interval=1m",
+		SNMPNotifierConfiguration{
+			alertparser.Configuration{
+				TrapDefaultOID:            "1.3.6.1.4.1.98789.1",
+				TrapOIDLabel:              "oid",
+				DefaultSeverity:           "critical",
+				SeverityLabel:             "severity",
+				Severities:                []string{"critical", "warning", "info"},
+				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
+				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
+			},
+			trapsender.Configuration{
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.2:163",
+						Address:   "127.0.0.2:163",
+						Version:   "V2c",
+						Retries:   1,
+						Timeout:   5 * time.Second,
+						Community: "public",
+					},
+				},
+				UserObjects:         make([]trapsender.UserObject, 0),
+				DescriptionType:     "string",
+				SendTimeout:         30 * time.Second,
+				SpoolDirectory:      "/var/spool/snmp_notifier",
+				SpoolMaxAge:         24 * time.Hour,
+				SpoolMaxSize:        1024 * 1024,
+				SpoolReplayInterval: time.Minute,
 			},
 			httpserver.Configuration{
 				ToolKitConfiguration: web.FlagConfig{
@@ -134,6 +367,9 @@ func TestV2Configuration(t *testing.T) {
 					WebListenAddresses: &testListenAddresses,
 				},
 			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
 		},
 		true,
 	)
@@ -155,13 +391,22 @@ func TestV3Configuration(t *testing.T) {
 				Severities:                []string{"critical", "error", "warning", "info"},
 				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
 				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
 			},
 			trapsender.Configuration{
-				SNMPVersion:             "V3",
-				SNMPDestination:         []string{"127.0.0.2:163"},
-				SNMPRetries:             4,
-				SNMPTimeout:             5 * time.Second,
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:    "127.0.0.2:163",
+						Address: "127.0.0.2:163",
+						Version: "V3",
+						Retries: 4,
+						Timeout: 5 * time.Second,
+					},
+				},
 				UserObjects:             make([]trapsender.UserObject, 0),
+				DescriptionType:         "string",
+				SendTimeout:             30 * time.Second,
 				SNMPEngineStartTimeUnix: 1750334785,
 			},
 			httpserver.Configuration{
@@ -171,6 +416,9 @@ func TestV3Configuration(t *testing.T) {
 					WebListenAddresses: &testListenAddresses,
 				},
 			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
 		},
 		false,
 	)
@@ -193,17 +441,26 @@ func TestV3AuthenticationConfiguration(t *testing.T) {
 				Severities:                []string{"critical", "error", "warning", "info"},
 				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
 				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
 			},
 			trapsender.Configuration{
-				SNMPVersion:                "V3",
-				SNMPDestination:            []string{"127.0.0.2:163"},
-				SNMPRetries:                4,
-				SNMPTimeout:                5 * time.Second,
-				SNMPAuthenticationEnabled:  true,
-				SNMPAuthenticationProtocol: "MD5",
-				SNMPAuthenticationUsername: "username_v3",
-				SNMPAuthenticationPassword: "password_v3",
-				UserObjects:                make([]trapsender.UserObject, 0),
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:                   "127.0.0.2:163",
+						Address:                "127.0.0.2:163",
+						Version:                "V3",
+						Retries:                4,
+						Timeout:                5 * time.Second,
+						AuthenticationEnabled:  true,
+						AuthenticationProtocol: "MD5",
+						AuthenticationUsername: "username_v3",
+						AuthenticationPassword: "password_v3",
+					},
+				},
+				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
 			},
 			httpserver.Configuration{
 				ToolKitConfiguration: web.FlagConfig{
@@ -212,6 +469,9 @@ func TestV3AuthenticationConfiguration(t *testing.T) {
 					WebListenAddresses: &testListenAddresses,
 				},
 			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
 		},
 		true,
 	)
@@ -234,20 +494,85 @@ func TestV3AuthenticationAndPrivateConfiguration(t *testing.T) {
 				Severities:                []string{"critical", "error", "warning", "info"},
 				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
 				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
+			},
+			trapsender.Configuration{
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:                   "127.0.0.2:163",
+						Address:                "127.0.0.2:163",
+						Version:                "V3",
+						Retries:                4,
+						Timeout:                5 * time.Second,
+						PrivateEnabled:         true,
+						PrivateProtocol:        "DES",
+						PrivatePassword:        "priv_password_v3",
+						AuthenticationEnabled:  true,
+						AuthenticationProtocol: "MD5",
+						AuthenticationUsername: "username_v3",
+						AuthenticationPassword: "password_v3",
+					},
+				},
+				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
+			},
+			httpserver.Configuration{
+				ToolKitConfiguration: web.FlagConfig{
+					WebSystemdSocket:   &falseValue,
+					WebConfigFile:      &emptyString,
+					WebListenAddresses: &testListenAddresses,
+				},
+			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
+		},
+		true,
+	)
+}
+
+func TestV3SHA2AndAES256Configuration(t *testing.T) {
+	expectConfigurationFromCommandLineAndEnvironmentVariables(
+		t, "--web.listen-address=:1234 --snmp.version=V3 --snmp.private-enabled --snmp.private-protocol=AES256 --snmp.authentication-enabled --snmp.authentication-protocol=SHA512 --trap.description-template=../description-template.tpl --snmp.destination=127.0.0.2:163 --snmp.retries=4 --trap.default-oid=4.4.4 --trap.oid-label=other-oid --alert.default-severity=warning --alert.severity-label=severity --alert.severities=critical,error,warning,info",
+		map[string]string{
+			"SNMP_NOTIFIER_AUTH_USERNAME": "username_v3",
+			"SNMP_NOTIFIER_AUTH_PASSWORD": "password_v3",
+			"SNMP_NOTIFIER_PRIV_PASSWORD": "priv_password_v3",
+		},
+		SNMPNotifierConfiguration{
+			alertparser.Configuration{
+				TrapDefaultOID:            "4.4.4",
+				TrapOIDLabel:              "other-oid",
+				DefaultSeverity:           "warning",
+				SeverityLabel:             "severity",
+				Severities:                []string{"critical", "error", "warning", "info"},
+				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
+				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
 			},
 			trapsender.Configuration{
-				SNMPVersion:                "V3",
-				SNMPDestination:            []string{"127.0.0.2:163"},
-				SNMPRetries:                4,
-				SNMPTimeout:                5 * time.Second,
-				SNMPPrivateEnabled:         true,
-				SNMPPrivateProtocol:        "DES",
-				SNMPPrivatePassword:        "priv_password_v3",
-				SNMPAuthenticationEnabled:  true,
-				SNMPAuthenticationProtocol: "MD5",
-				SNMPAuthenticationUsername: "username_v3",
-				SNMPAuthenticationPassword: "password_v3",
-				UserObjects:                make([]trapsender.UserObject, 0),
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:                   "127.0.0.2:163",
+						Address:                "127.0.0.2:163",
+						Version:                "V3",
+						Retries:                4,
+						Timeout:                5 * time.Second,
+						PrivateEnabled:         true,
+						PrivateProtocol:        "AES256",
+						PrivatePassword:        "priv_password_v3",
+						AuthenticationEnabled:  true,
+						AuthenticationProtocol: "SHA512",
+						AuthenticationUsername: "username_v3",
+						AuthenticationPassword: "password_v3",
+					},
+				},
+				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
 			},
 			httpserver.Configuration{
 				ToolKitConfiguration: web.FlagConfig{
@@ -256,6 +581,9 @@ func TestV3AuthenticationAndPrivateConfiguration(t *testing.T) {
 					WebListenAddresses: &testListenAddresses,
 				},
 			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
 		},
 		true,
 	)
@@ -275,14 +603,23 @@ func TestConfigurationWithDifferentResolvedTrapOIDConfiguration(t *testing.T) {
 				Severities:                []string{"critical", "warning", "info"},
 				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
 				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
 			},
 			trapsender.Configuration{
-				SNMPVersion:     "V2c",
-				SNMPDestination: []string{"127.0.0.1:162"},
-				SNMPRetries:     1,
-				SNMPTimeout:     5 * time.Second,
-				SNMPCommunity:   "public",
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.1:162",
+						Address:   "127.0.0.1:162",
+						Version:   "V2c",
+						Retries:   1,
+						Timeout:   5 * time.Second,
+						Community: "public",
+					},
+				},
 				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
 			},
 			httpserver.Configuration{
 				ToolKitConfiguration: web.FlagConfig{
@@ -291,6 +628,9 @@ func TestConfigurationWithDifferentResolvedTrapOIDConfiguration(t *testing.T) {
 					WebListenAddresses: &testListenAddresses,
 				},
 			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
 		},
 		true,
 	)
@@ -311,14 +651,23 @@ func TestConfigurationWithDifferentResolvedTrapLabelOIDConfiguration(t *testing.
 				Severities:                []string{"critical", "warning", "info"},
 				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
 				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
 			},
 			trapsender.Configuration{
-				SNMPVersion:     "V2c",
-				SNMPDestination: []string{"127.0.0.1:162"},
-				SNMPRetries:     1,
-				SNMPTimeout:     5 * time.Second,
-				SNMPCommunity:   "public",
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.1:162",
+						Address:   "127.0.0.1:162",
+						Version:   "V2c",
+						Retries:   1,
+						Timeout:   5 * time.Second,
+						Community: "public",
+					},
+				},
 				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
 			},
 			httpserver.Configuration{
 				ToolKitConfiguration: web.FlagConfig{
@@ -327,56 +676,600 @@ func TestConfigurationWithDifferentResolvedTrapLabelOIDConfiguration(t *testing.
 					WebListenAddresses: &testListenAddresses,
 				},
 			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
 		},
 		true,
 	)
 }
 
-func TestMalFormedTrapOID(t *testing.T) {
-	expectConfigurationFromCommandLineError(
-		t,
-		"--trap.default-oid=A.1.1.1 --trap.description-template=../description-template.tpl",
-	)
-}
-
-func TestMalFormedTrapBaseOID(t *testing.T) {
-	expectConfigurationFromCommandLineError(
-		t,
-		"--trap.default-objects-base-oid=A.1.1.1 --trap.description-template=../description-template.tpl",
-	)
-}
-
-func TestMalFormedResolutionTrapOID(t *testing.T) {
-	expectConfigurationFromCommandLineError(
-		t,
-		"--trap.resolution-default-oid=A.1.1.1 --trap.description-template=../description-template.tpl",
-	)
-}
-
-func TestMalFormedTrapUserObjectsOID(t *testing.T) {
-	expectConfigurationFromCommandLineError(
-		t,
-		"--trap.user-objects-base-oid=A.1.1.1 --trap.description-template=../description-template.tpl",
+func TestConfigurationFile(t *testing.T) {
+	expectConfigurationFromCommandLine(t,
+		"--config.file=test_configuration.yml",
+		SNMPNotifierConfiguration{
+			alertparser.Configuration{
+				TrapDefaultOID:            "4.4.4",
+				TrapOIDLabel:              "other-oid",
+				DefaultSeverity:           "warning",
+				SeverityLabel:             "severity",
+				Severities:                []string{"critical", "error", "warning", "info"},
+				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
+				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-alert",
+				TrapAlertID:               "hash",
+			},
+			trapsender.Configuration{
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.2:163",
+						Address:   "127.0.0.2:163",
+						Version:   "V2c",
+						Retries:   4,
+						Timeout:   10 * time.Second,
+						Community: "private",
+					},
+				},
+				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     time.Minute,
+			},
+			httpserver.Configuration{
+				ToolKitConfiguration: web.FlagConfig{
+					WebSystemdSocket:   &falseValue,
+					WebConfigFile:      &emptyString,
+					WebListenAddresses: &testListenAddresses,
+				},
+			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
+		},
+		true,
 	)
 }
 
-func TestConfigurationMixingV2AndV3Elements(t *testing.T) {
-	expectConfigurationFromCommandLineError(
-		t,
-		"--web.listen-address=:1234 --snmp.version=V3 --snmp.private-enabled --trap.description-template=../description-template.tpl --snmp.destination=127.0.0.2:163 --snmp.retries=4 --trap.default-oid=4.4.4 --trap.oid-label=other-oid --alert.default-severity=warning --alert.severity-label=severity --alert.severities=critical,error,warning,info",
+func TestConfigurationFileOverriddenByCommandLine(t *testing.T) {
+	expectConfigurationFromCommandLineAndEnvironmentVariables(t,
+		"--config.file test_configuration.yml --snmp.timeout=2s --alert.default-severity=critical",
+		map[string]string{
+			"SNMP_NOTIFIER_COMMUNITY": "secret",
+		},
+		SNMPNotifierConfiguration{
+			alertparser.Configuration{
+				TrapDefaultOID:            "4.4.4",
+				TrapOIDLabel:              "other-oid",
+				DefaultSeverity:           "critical",
+				SeverityLabel:             "severity",
+				Severities:                []string{"critical", "error", "warning", "info"},
+				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
+				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-alert",
+				TrapAlertID:               "hash",
+			},
+			trapsender.Configuration{
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.2:163",
+						Address:   "127.0.0.2:163",
+						Version:   "V2c",
+						Retries:   4,
+						Timeout:   2 * time.Second,
+						Community: "secret",
+					},
+				},
+				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     time.Minute,
+			},
+			httpserver.Configuration{
+				ToolKitConfiguration: web.FlagConfig{
+					WebSystemdSocket:   &falseValue,
+					WebConfigFile:      &emptyString,
+					WebListenAddresses: &testListenAddresses,
+				},
+			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
+		},
+		true,
 	)
 }
 
-func TestConfigurationMixingV2AndV3AuthenticationAndPrivate(t *testing.T) {
-	expectConfigurationFromCommandLineError(
-		t,
-		"--web.listen-address=:1234 --snmp.version=V2c --snmp.private-enabled --snmp.authentication-enabled --trap.description-template=../description-template.tpl --snmp.destination=127.0.0.2:163 --snmp.retries=4 --trap.default-oid=4.4.4 --trap.oid-label=other-oid --alert.default-severity=warning --alert.severity-label=severity --alert.severities=critical,error,warning,info",
+func TestConfigurationFileWithDestinations(t *testing.T) {
+	expectConfigurationFromCommandLine(t,
+		"--web.listen-address=:1234 --config.file=test_destinations_configuration.yml",
+		SNMPNotifierConfiguration{
+			alertparser.Configuration{
+				TrapDefaultOID:            "1.3.6.1.4.1.98789.1",
+				TrapOIDLabel:              "oid",
+				DefaultSeverity:           "critical",
+				SeverityLabel:             "severity",
+				Severities:                []string{"critical", "warning", "info"},
+				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
+				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
+			},
+			trapsender.Configuration{
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.2:162",
+						Address:   "127.0.0.2:162",
+						Version:   "V2c",
+						Retries:   2,
+						Timeout:   5 * time.Second,
+						Community: "legacy",
+					},
+					{
+						Name:                   "127.0.0.3:162",
+						Address:                "127.0.0.3:162",
+						Version:                "V3",
+						Retries:                2,
+						Timeout:                10 * time.Second,
+						AuthenticationEnabled:  true,
+						AuthenticationProtocol: "SHA",
+						AuthenticationUsername: "noc",
+						AuthenticationPassword: "noc_password",
+						SecurityEngineID:       "8000000004736e6d70676f",
+					},
+					{
+						Name:                   "127.0.0.4:162",
+						Address:                "127.0.0.4:162",
+						Version:                "V3",
+						Retries:                4,
+						Timeout:                5 * time.Second,
+						Inform:                 true,
+						AuthenticationEnabled:  true,
+						AuthenticationProtocol: "SHA",
+						AuthenticationUsername: "datacenter",
+						AuthenticationPassword: "datacenter_password",
+						PrivateEnabled:         true,
+						PrivateProtocol:        "AES",
+						PrivatePassword:        "datacenter_secret",
+					},
+				},
+				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
+			},
+			httpserver.Configuration{
+				ToolKitConfiguration: web.FlagConfig{
+					WebSystemdSocket:   &falseValue,
+					WebConfigFile:      &emptyString,
+					WebListenAddresses: &testListenAddresses,
+				},
+			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
+		},
+		true,
 	)
 }
 
-func TestConfigurationMixingV2AndV3Authentication(t *testing.T) {
-	expectConfigurationFromCommandLineError(
-		t,
+func TestConfigurationFileDestinationsOverriddenByCommandLine(t *testing.T) {
+	expectConfigurationFromCommandLine(t,
+		"--web.listen-address=:1234 --config.file=test_destinations_configuration.yml --snmp.destination=127.0.0.5:162",
+		SNMPNotifierConfiguration{
+			alertparser.Configuration{
+				TrapDefaultOID:            "1.3.6.1.4.1.98789.1",
+				TrapOIDLabel:              "oid",
+				DefaultSeverity:           "critical",
+				SeverityLabel:             "severity",
+				Severities:                []string{"critical", "warning", "info"},
+				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
+				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
+			},
+			trapsender.Configuration{
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "127.0.0.5:162",
+						Address:   "127.0.0.5:162",
+						Version:   "V2c",
+						Retries:   2,
+						Timeout:   5 * time.Second,
+						Community: "legacy",
+					},
+				},
+				UserObjects:     make([]trapsender.UserObject, 0),
+				DescriptionType: "string",
+				SendTimeout:     30 * time.Second,
+			},
+			httpserver.Configuration{
+				ToolKitConfiguration: web.FlagConfig{
+					WebSystemdSocket:   &falseValue,
+					WebConfigFile:      &emptyString,
+					WebListenAddresses: &testListenAddresses,
+				},
+			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
+		},
+		true,
+	)
+}
+
+func TestConfigurationFileWithInvalidDestination(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--config.file=test_invalid_destination_configuration.yml",
+	)
+}
+
+func TestTypedUserObjects(t *testing.T) {
+	expectUserObjectTypes(t,
+		"--trap.description-template=../description-template.tpl --trap.description-type=oid --trap.user-object=4=../description-template.tpl --trap.user-object=5=integer:../description-template.tpl",
+		"oid",
+		[]string{"string", "integer"},
+	)
+}
+
+func TestConfigurationFileWithTypedUserObjects(t *testing.T) {
+	expectUserObjectTypes(t,
+		"--config.file=test_user_objects_configuration.yml",
+		"string",
+		[]string{"string", "gauge32"},
+	)
+}
+
+func TestGenerateMIBCommand(t *testing.T) {
+	os.Clearenv()
+	command, configuration, _, err := ParseCommandLine(strings.Split("generate-mib --config.file=test_user_objects_configuration.yml --module-name=ACME-ALERTS-MIB --output=acme.my", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expectedCommand := Command{
+		Name:          GenerateMIBCommand,
+		MIBModuleName: "ACME-ALERTS-MIB",
+		MIBModuleOID:  "1.3.6.1.4.1.98789.100",
+		MIBOutput:     "acme.my",
+	}
+	if diff := deep.Equal(*command, expectedCommand); diff != nil {
+		t.Error(diff)
+	}
+
+	userObjects := configuration.TrapSenderConfiguration.UserObjects
+	if userObjects[0].Name != "" || userObjects[1].Name != "snmpNotifierAlertCount" || userObjects[1].Description != "The number of alerts of the trap." {
+		t.Error("unexpected user object names", "userObjects", userObjects)
+	}
+}
+
+func TestRenderCommand(t *testing.T) {
+	os.Clearenv()
+	command, _, _, err := ParseCommandLine(strings.Split("render --format=json --trap.description-template=../description-template.tpl ../alertparser/test_mixed_alerts.json", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if command.Name != RenderCommand || command.AlertsFile != "../alertparser/test_mixed_alerts.json" || command.RenderFormat != JSONRenderFormat {
+		t.Error("unexpected render command", "command", command)
+	}
+}
+
+func TestDryRunConfiguration(t *testing.T) {
+	os.Clearenv()
+	_, configuration, _, err := ParseCommandLine(strings.Split("--trap.description-template=../description-template.tpl --snmp.dry-run --snmp.dry-run-file=traps.jsonl", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !configuration.TrapSenderConfiguration.DryRun || configuration.TrapSenderConfiguration.DryRunFile != "traps.jsonl" {
+		t.Error("unexpected dry run configuration", "configuration", configuration.TrapSenderConfiguration)
+	}
+}
+
+func TestRenotifyConfiguration(t *testing.T) {
+	os.Clearenv()
+	_, configuration, _, err := ParseCommandLine(strings.Split("--config.file=test_renotify_configuration.yml --alert.renotify-interval=info=30s", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	expected := alertstore.Configuration{
+		RenotifyIntervals: map[string]time.Duration{"info": 30 * time.Second},
+		StateFile:         "alerts.json",
+		MaxAge:            12 * time.Hour,
+	}
+	if diff := deep.Equal(configuration.AlertStoreConfiguration, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	_, configuration, _, err = ParseCommandLine(strings.Split("--config.file=test_renotify_configuration.yml", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	expected.RenotifyIntervals = map[string]time.Duration{"critical": 5 * time.Minute, "warning": time.Hour}
+	if diff := deep.Equal(configuration.AlertStoreConfiguration, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestAgentConfiguration(t *testing.T) {
+	os.Clearenv()
+	_, configuration, _, err := ParseCommandLine(strings.Split("--config.file=test_agent_configuration.yml", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if diff := deep.Equal(configuration.AgentConfiguration, snmpagent.Configuration{ListenAddress: "127.0.0.1:1161", Community: "alerts"}); diff != nil {
+		t.Error(diff)
+	}
+	if !configuration.AlertStoreConfiguration.KeepAlerts {
+		t.Error("the alert groups should be kept for the SNMP agent")
+	}
+
+	os.Setenv("SNMP_NOTIFIER_AGENT_COMMUNITY", "secret")
+	_, configuration, _, err = ParseCommandLine(strings.Split("--config.file=test_agent_configuration.yml --agent.listen-address=:1162", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if diff := deep.Equal(configuration.AgentConfiguration, snmpagent.Configuration{ListenAddress: ":1162", Community: "secret"}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestHeartbeatConfiguration(t *testing.T) {
+	os.Clearenv()
+	_, configuration, _, err := ParseCommandLine(strings.Split("--config.file=test_heartbeat_configuration.yml", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if diff := deep.Equal(configuration.HeartbeatConfiguration, heartbeat.Configuration{Interval: 5 * time.Minute, TrapOID: "1.2.3.5"}); diff != nil {
+		t.Error(diff)
+	}
+
+	_, configuration, _, err = ParseCommandLine(strings.Split("--config.file=test_heartbeat_configuration.yml --heartbeat.interval=30s --trap.mib-directory=../mibs --heartbeat.oid=snmpNotifierHeartbeatTrap", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if diff := deep.Equal(configuration.HeartbeatConfiguration, heartbeat.Configuration{Interval: 30 * time.Second, TrapOID: "1.3.6.1.4.1.98789.5"}); diff != nil {
+		t.Error(diff)
+	}
+
+	_, configuration, _, err = ParseCommandLine(strings.Split("--config.file=test_heartbeat_configuration.yml --heartbeat.interval=0s", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if diff := deep.Equal(configuration.HeartbeatConfiguration, heartbeat.Configuration{}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestConfigurationWithInvalidHeartbeat(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--heartbeat.interval=-1m --trap.description-template=../description-template.tpl",
+	)
+	expectConfigurationFromCommandLineError(
+		t,
+		"--heartbeat.interval=1m --heartbeat.oid=1.a --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationFileRelativePaths(t *testing.T) {
+	os.Clearenv()
+	directory, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	configurationFile := filepath.Join(directory, "test_paths_configuration.yml")
+	// the paths of the configuration file do not depend on the working directory
+	t.Chdir(t.TempDir())
+
+	_, configuration, _, err := ParseCommandLine([]string{"--config.file=" + configurationFile})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if configuration.AlertParserConfiguration.TrapDefaultOID != "1.3.6.1.4.1.98789.1" {
+		t.Error("the MIB directory should be relative to the configuration file", configuration.AlertParserConfiguration.TrapDefaultOID)
+	}
+	if diff := deep.Equal(configuration.AlertStoreConfiguration.StateFile, filepath.Join(directory, "state", "alerts.json")); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(configuration.TrapSenderConfiguration.DryRunFile, filepath.Join(directory, "traps.jsonl")); diff != nil {
+		t.Error(diff)
+	}
+	userObjectTemplates := map[int]string{}
+	for _, userObject := range configuration.TrapSenderConfiguration.UserObjects {
+		userObjectTemplates[userObject.SubOID] = userObject.ContentTemplate.Name()
+	}
+	if diff := deep.Equal(userObjectTemplates, map[int]string{4: "description-template.tpl", 5: "null"}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestDefaultCommand(t *testing.T) {
+	os.Clearenv()
+	command, _, _, err := ParseCommandLine(strings.Split("--trap.description-template=../description-template.tpl", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if command.Name != RunCommand {
+		t.Error("unexpected default command", "command", command.Name)
+	}
+}
+
+func TestConfigurationFileWithInvalidUserObjectName(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--config.file=test_invalid_user_object_name_configuration.yml",
+	)
+}
+
+func TestConfigurationErrorsAreAllReported(t *testing.T) {
+	os.Clearenv()
+	command, configuration, _, err := ParseCommandLine(strings.Split("check-config --trap.description-template=../description-template.tpl --trap.default-oid=1.a --snmp.queue-size=-1 --trap.max-alerts-per-trap=-1 --snmp.spool-directory=spool --snmp.spool-replay-interval=0s", " "))
+	if configuration != nil || err == nil {
+		t.Fatal("an error was expected")
+	}
+	if command.Name != CheckConfigCommand {
+		t.Error("unexpected command", "command", command.Name)
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 4 {
+		t.Error("every problem should be reported", "err", err)
+	}
+}
+
+func TestCheckTemplates(t *testing.T) {
+	os.Clearenv()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	configuration, _, err := ParseConfiguration(strings.Split("--trap.description-template=../description-template.tpl --trap.user-object=4=../description-template.tpl --trap.user-object=6=test_functions_template.tpl --alert.severities=major,minor --trap.grouping=per-alert", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err := CheckTemplates(*configuration, logger); err != nil {
+		t.Error("unexpected error", err)
+	}
+
+	configuration, _, err = ParseConfiguration(strings.Split("--trap.description-template=test_invalid_template.tpl --trap.user-object=4=integer:../description-template.tpl --trap.user-object=5=test_invalid_template.tpl", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	err = CheckTemplates(*configuration, logger)
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 3 {
+		t.Error("every invalid template should be reported once", "err", err)
+	}
+}
+
+func TestConfigurationFileWithRoutes(t *testing.T) {
+	networkMatcher, _ := labels.NewMatcher(labels.MatchEqual, "team", "network")
+	expectConfigurationFromCommandLine(t,
+		"--web.listen-address=:1234 --config.file=test_routes_configuration.yml",
+		SNMPNotifierConfiguration{
+			alertparser.Configuration{
+				TrapDefaultOID:            "1.3.6.1.4.1.98789.1",
+				TrapOIDLabel:              "oid",
+				DefaultSeverity:           "critical",
+				SeverityLabel:             "severity",
+				Severities:                []string{"critical", "warning", "info"},
+				TrapDefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
+				TrapUserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+				TrapGrouping:              "per-oid",
+				TrapAlertID:               "key",
+			},
+			trapsender.Configuration{
+				SNMPDestinations: []trapsender.Destination{
+					{
+						Name:      "network",
+						Address:   "127.0.0.2:162",
+						Version:   "V2c",
+						Retries:   1,
+						Timeout:   5 * time.Second,
+						Community: "public",
+					},
+					{
+						Name:      "datacenter",
+						Address:   "127.0.0.3:162",
+						Version:   "V2c",
+						Retries:   1,
+						Timeout:   5 * time.Second,
+						Community: "public",
+					},
+				},
+				Routes: []trapsender.Route{
+					{
+						Matchers:     labels.Matchers{networkMatcher},
+						Destinations: []string{"network"},
+					},
+					{
+						Receiver:     "snmp-datacenter",
+						Matchers:     labels.Matchers{},
+						Destinations: []string{"datacenter"},
+					},
+				},
+				DefaultDestinations: []string{"datacenter"},
+				UserObjects:         make([]trapsender.UserObject, 0),
+				DescriptionType:     "string",
+				SendTimeout:         30 * time.Second,
+			},
+			httpserver.Configuration{
+				ToolKitConfiguration: web.FlagConfig{
+					WebSystemdSocket:   &falseValue,
+					WebConfigFile:      &emptyString,
+					WebListenAddresses: &testListenAddresses,
+				},
+			},
+			alertstore.Configuration{MaxAge: 24 * time.Hour},
+			snmpagent.Configuration{},
+			heartbeat.Configuration{},
+		},
+		true,
+	)
+}
+
+func TestConfigurationFileWithInvalidRoutes(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--config.file=test_invalid_routes_configuration.yml",
+	)
+}
+
+func TestConfigurationFileWithUnknownKey(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--config.file=test_unknown_key_configuration.yml --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestMissingConfigurationFile(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--config.file=non_existing_configuration.yml --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestMalFormedTrapOID(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--trap.default-oid=A.1.1.1 --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestMalFormedTrapBaseOID(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--trap.default-objects-base-oid=A.1.1.1 --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestMalFormedResolutionTrapOID(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--trap.resolution-default-oid=A.1.1.1 --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestMalFormedTrapUserObjectsOID(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--trap.user-objects-base-oid=A.1.1.1 --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationMixingV2AndV3Elements(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--web.listen-address=:1234 --snmp.version=V3 --snmp.private-enabled --trap.description-template=../description-template.tpl --snmp.destination=127.0.0.2:163 --snmp.retries=4 --trap.default-oid=4.4.4 --trap.oid-label=other-oid --alert.default-severity=warning --alert.severity-label=severity --alert.severities=critical,error,warning,info",
+	)
+}
+
+func TestConfigurationMixingV2AndV3AuthenticationAndPrivate(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--web.listen-address=:1234 --snmp.version=V2c --snmp.private-enabled --snmp.authentication-enabled --trap.description-template=../description-template.tpl --snmp.destination=127.0.0.2:163 --snmp.retries=4 --trap.default-oid=4.4.4 --trap.oid-label=other-oid --alert.default-severity=warning --alert.severity-label=severity --alert.severities=critical,error,warning,info",
+	)
+}
+
+func TestConfigurationMixingV2AndV3Authentication(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
 		"--web.listen-address=:1234 --snmp.version=V2c --snmp.authentication-enabled --trap.description-template=../description-template.tpl --snmp.destination=127.0.0.2:163 --snmp.retries=4 --trap.default-oid=4.4.4 --trap.oid-label=other-oid --alert.default-severity=warning --alert.severity-label=severity --alert.severities=critical,error,warning,info",
 	)
 }
@@ -388,6 +1281,148 @@ func TestConfigurationMixingV2AndV3Private(t *testing.T) {
 	)
 }
 
+func TestConfigurationWithV1Informs(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--snmp.version=V1 --snmp.inform --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationWithInvalidV1AgentAddress(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--snmp.version=V1 --snmp.agent-address=::1 --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationMixingV1AndV3Authentication(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--snmp.version=V1 --snmp.authentication-enabled --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationWithInvalidQueueWorkers(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--snmp.queue-size=10 --snmp.queue-workers=0 --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationWithInvalidQueueRetryBackoffs(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--snmp.queue-size=10 --snmp.queue-retry-backoff=0s --trap.description-template=../description-template.tpl",
+	)
+	expectConfigurationFromCommandLineError(
+		t,
+		"--snmp.queue-size=10 --snmp.queue-retry-backoff=2m --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationWithInvalidSpoolReplayInterval(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--snmp.spool-directory=/tmp --snmp.spool-replay-interval=0s --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationWithInvalidUserObjectType(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--trap.user-object=5=float:../description-template.tpl --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationWithMissingSeverityLevel(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--alert.severity-level=critical=5 --alert.severity-level=warning=3 --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationWithUnknownSeverityLevel(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--alert.severity-level=critical=5 --alert.severity-level=warning=3 --alert.severity-level=info=1 --alert.severity-level=debug=0 --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationWithNonPositiveSeverityLevel(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--alert.severity-level=critical=5 --alert.severity-level=warning=3 --alert.severity-level=info=0 --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationWithUnknownRenotifySeverity(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--alert.renotify-interval=debug=5m --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationWithNegativeAlertMaxAge(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--alert.max-age=-1h --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestConfigurationWithInvalidRenotifyInterval(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--alert.renotify-interval=critical=0s --trap.description-template=../description-template.tpl",
+	)
+	expectConfigurationFromCommandLineError(
+		t,
+		"--alert.renotify-interval=critical=soon --trap.description-template=../description-template.tpl",
+	)
+}
+
+func TestSymbolicOIDs(t *testing.T) {
+	os.Clearenv()
+	configuration, _, err := ParseConfiguration(strings.Split("--trap.description-template=../description-template.tpl --trap.mib-directory=../mibs --trap.default-oid=SNMP-NOTIFIER-MIB::snmpNotifierDefaultTrap --trap.resolution-default-oid=snmpNotifier.4 --trap.user-objects-base-oid=SNMP-NOTIFIER-MIB::snmpNotifierAlertsUserObjects", " "))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	alertParserConfiguration := configuration.AlertParserConfiguration
+	if alertParserConfiguration.TrapDefaultOID != "1.3.6.1.4.1.98789.1" {
+		t.Error("unexpected default trap OID", alertParserConfiguration.TrapDefaultOID)
+	}
+	if *alertParserConfiguration.TrapResolutionDefaultOID != "1.3.6.1.4.1.98789.4" {
+		t.Error("unexpected resolution trap OID", *alertParserConfiguration.TrapResolutionDefaultOID)
+	}
+	if alertParserConfiguration.TrapUserObjectsBaseOID != "1.3.6.1.4.1.98789.3" {
+		t.Error("unexpected user objects base OID", alertParserConfiguration.TrapUserObjectsBaseOID)
+	}
+	if alertParserConfiguration.OIDResolver == nil {
+		t.Error("the MIB modules should be passed to the alert parser")
+	}
+}
+
+func TestConfigurationWithUnknownSymbolicOID(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--trap.description-template=../description-template.tpl --trap.mib-directory=../mibs --trap.default-oid=SNMP-NOTIFIER-MIB::snmpNotifierUnknownTrap",
+	)
+}
+
+func TestConfigurationWithSymbolicOIDWithoutMIBDirectory(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--trap.description-template=../description-template.tpl --trap.default-oid=SNMP-NOTIFIER-MIB::snmpNotifierDefaultTrap",
+	)
+}
+
+func TestConfigurationWithUnknownMIBDirectory(t *testing.T) {
+	expectConfigurationFromCommandLineError(
+		t,
+		"--trap.description-template=../description-template.tpl --trap.mib-directory=unknown_directory",
+	)
+}
+
 func expectConfigurationFromCommandLine(t *testing.T, commandLine string, configuration SNMPNotifierConfiguration, ignoreStartUpTime bool) {
 	expectConfigurationFromCommandLineAndEnvironmentVariables(
 		t,
@@ -431,6 +1466,25 @@ func expectConfigurationFromCommandLineAndEnvironmentVariables(t *testing.T, com
 	}
 }
 
+func expectUserObjectTypes(t *testing.T, commandLine string, descriptionType string, userObjectTypes []string) {
+	os.Clearenv()
+	parsedConfiguration, _, err := ParseConfiguration(strings.Split(commandLine, " "))
+	if err != nil {
+		t.Fatal("error occured and no expected error", "err", err)
+	}
+
+	if parsedConfiguration.TrapSenderConfiguration.DescriptionType != descriptionType {
+		t.Error("unexpected description type", "type", parsedConfiguration.TrapSenderConfiguration.DescriptionType)
+	}
+	types := []string{}
+	for _, userObject := range parsedConfiguration.TrapSenderConfiguration.UserObjects {
+		types = append(types, userObject.Type)
+	}
+	if diff := deep.Equal(types, userObjectTypes); diff != nil {
+		t.Error(diff)
+	}
+}
+
 func expectConfigurationFromCommandLineError(t *testing.T, commandLine string) {
 	expectConfigurationErrorFromCommandLineAndEnvironmentVariables(
 		t,
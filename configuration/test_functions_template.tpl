@@ -0,0 +1,2 @@
+{{ range .Alerts }}{{ .Labels.alertname | toUpper }} since {{ .StartsAt | formatTime "2006-01-02 15:04" }} ({{ .StartsAt | since }}): {{ .Annotations.summary | default "no summary" | ascii | truncate 64 }} [{{ .Labels | sortedLabels | join ", " }}]
+{{ end }}
@@ -0,0 +1 @@
+{{ .UnknownField }}
@@ -0,0 +1,134 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package heartbeat
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/maxwo/snmp_notifier/telemetry"
+	"github.com/maxwo/snmp_notifier/trapsender"
+)
+
+// the start of the SNMP notifier, which the uptime of the heartbeats is measured from
+var startTime = time.Now()
+
+// Heartbeat sends heartbeat traps periodically, so that the SNMP managers may raise an alarm when they stop
+type Heartbeat struct {
+	logger        *slog.Logger
+	configuration Configuration
+	trapSender    trapsender.TrapSender
+	mutex         sync.Mutex
+	updateChannel chan struct{}
+	stopChannel   chan struct{}
+	stopOnce      sync.Once
+}
+
+// Configuration describes the heartbeat traps
+type Configuration struct {
+	// Interval is the interval between two heartbeat traps, the heartbeat being disabled when 0
+	Interval time.Duration
+	TrapOID  string
+}
+
+// New creates a Heartbeat
+func New(configuration Configuration, trapSender trapsender.TrapSender, logger *slog.Logger) *Heartbeat {
+	return &Heartbeat{
+		logger:        logger,
+		configuration: configuration,
+		trapSender:    trapSender,
+		updateChannel: make(chan struct{}, 1),
+		stopChannel:   make(chan struct{}),
+	}
+}
+
+// Update replaces the configuration and the trap sender, the next heartbeat being sent after the new interval
+func (heartbeat *Heartbeat) Update(configuration Configuration, trapSender trapsender.TrapSender) {
+	heartbeat.mutex.Lock()
+	heartbeat.configuration = configuration
+	heartbeat.trapSender = trapSender
+	heartbeat.mutex.Unlock()
+
+	select {
+	case heartbeat.updateChannel <- struct{}{}:
+	default:
+		// an update is already pending
+	}
+}
+
+// Start sends a heartbeat trap at once, then at every interval in the background, until the heartbeat is stopped
+func (heartbeat *Heartbeat) Start() {
+	go func() {
+		if configuration, _ := heartbeat.get(); configuration.Interval > 0 {
+			heartbeat.send()
+		}
+		for {
+			configuration, _ := heartbeat.get()
+			// without interval, the heartbeat waits for an update
+			var timer *time.Timer
+			var tick <-chan time.Time
+			if configuration.Interval > 0 {
+				timer = time.NewTimer(configuration.Interval)
+				tick = timer.C
+			}
+
+			select {
+			case <-heartbeat.stopChannel:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case <-heartbeat.updateChannel:
+				if timer != nil {
+					timer.Stop()
+				}
+			case <-tick:
+				heartbeat.send()
+			}
+		}
+	}()
+}
+
+// Stop stops sending heartbeat traps
+func (heartbeat *Heartbeat) Stop() {
+	heartbeat.stopOnce.Do(func() {
+		close(heartbeat.stopChannel)
+	})
+}
+
+func (heartbeat *Heartbeat) get() (Configuration, trapsender.TrapSender) {
+	heartbeat.mutex.Lock()
+	defer heartbeat.mutex.Unlock()
+	return heartbeat.configuration, heartbeat.trapSender
+}
+
+// send sends a heartbeat trap to every destination, with the uptime and the traps counted since the start
+func (heartbeat *Heartbeat) send() {
+	heartbeat.mutex.Lock()
+	configuration, trapSender := heartbeat.configuration, heartbeat.trapSender
+	trapSender.Acquire()
+	heartbeat.mutex.Unlock()
+	defer trapSender.Release()
+
+	err := trapSender.SendHeartbeat(trapsender.Heartbeat{
+		TrapOID:     configuration.TrapOID,
+		Uptime:      time.Since(startTime),
+		SentTraps:   telemetry.TrapCount("success"),
+		FailedTraps: telemetry.TrapCount("failure", "dropped"),
+	})
+	if err != nil {
+		heartbeat.logger.Error("error while sending the heartbeat trap", "err", err.Error())
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package heartbeat
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/maxwo/snmp_notifier/trapsender"
+)
+
+const heartbeatOID = "1.3.6.1.4.1.98789.5"
+
+func TestHeartbeatTraps(t *testing.T) {
+	dryRunFile := filepath.Join(t.TempDir(), "traps.jsonl")
+	heartbeat := New(Configuration{Interval: 20 * time.Millisecond, TrapOID: heartbeatOID}, newTrapSender(dryRunFile), newLogger())
+	heartbeat.Start()
+	time.Sleep(100 * time.Millisecond)
+	heartbeat.Stop()
+	heartbeat.Stop()
+	// a heartbeat trap may still be written
+	time.Sleep(20 * time.Millisecond)
+
+	traps := readDryRunFile(t, dryRunFile)
+	destinations := map[string]int{}
+	for _, trap := range traps {
+		destinations[trap.Destinations[0]]++
+	}
+	if destinations["first"] < 2 || destinations["second"] < 2 {
+		t.Fatalf("every destination should receive several heartbeat traps, got %v", destinations)
+	}
+	for _, trap := range traps {
+		if trap.TrapOID != heartbeatOID || trap.GroupID != "" {
+			t.Errorf("unexpected heartbeat trap %v", trap)
+		}
+		expected := []trapsender.RenderedVarBind{
+			{OID: "1.3.6.1.2.1.1.3.0", Type: "TimeTicks"},
+			{OID: "1.3.6.1.6.3.1.1.4.1.0", Type: "Oid", Value: heartbeatOID},
+			{OID: trapsender.HeartbeatObjectsOID + ".1", Type: "TimeTicks"},
+			{OID: trapsender.HeartbeatObjectsOID + ".2", Type: "Counter32"},
+			{OID: trapsender.HeartbeatObjectsOID + ".3", Type: "Counter32"},
+		}
+		if len(trap.VarBinds) != len(expected) {
+			t.Fatalf("unexpected heartbeat variable bindings %v", trap.VarBinds)
+		}
+		for index, varBind := range expected {
+			actual := trap.VarBinds[index]
+			if actual.OID != varBind.OID || actual.Type != varBind.Type || (varBind.Value != "" && actual.Value != varBind.Value) {
+				t.Errorf("unexpected variable binding: expected %v, got %v", varBind, actual)
+			}
+		}
+	}
+
+	count := len(traps)
+	time.Sleep(50 * time.Millisecond)
+	if traps := readDryRunFile(t, dryRunFile); len(traps) != count {
+		t.Error("no heartbeat trap should be sent once stopped")
+	}
+}
+
+func TestDisabledHeartbeat(t *testing.T) {
+	dryRunFile := filepath.Join(t.TempDir(), "traps.jsonl")
+	heartbeat := New(Configuration{}, newTrapSender(dryRunFile), newLogger())
+	heartbeat.Start()
+	defer heartbeat.Stop()
+	time.Sleep(50 * time.Millisecond)
+
+	if traps := readDryRunFile(t, dryRunFile); len(traps) != 0 {
+		t.Errorf("no heartbeat trap expected, got %d", len(traps))
+	}
+}
+
+func TestUpdatedHeartbeat(t *testing.T) {
+	dryRunFile := filepath.Join(t.TempDir(), "traps.jsonl")
+	heartbeat := New(Configuration{}, newTrapSender(dryRunFile), newLogger())
+	heartbeat.Start()
+	defer heartbeat.Stop()
+
+	heartbeat.Update(Configuration{Interval: 20 * time.Millisecond, TrapOID: "1.2.3"}, newTrapSender(dryRunFile))
+	time.Sleep(100 * time.Millisecond)
+	traps := readDryRunFile(t, dryRunFile)
+	if len(traps) == 0 {
+		t.Fatal("heartbeat traps expected once enabled")
+	}
+	if traps[0].TrapOID != "1.2.3" {
+		t.Errorf("the updated trap OID should be used, got %s", traps[0].TrapOID)
+	}
+
+	heartbeat.Update(Configuration{}, newTrapSender(dryRunFile))
+	time.Sleep(20 * time.Millisecond)
+	count := len(readDryRunFile(t, dryRunFile))
+	time.Sleep(60 * time.Millisecond)
+	if traps := readDryRunFile(t, dryRunFile); len(traps) != count {
+		t.Error("no heartbeat trap should be sent once disabled")
+	}
+}
+
+func newTrapSender(dryRunFile string) trapsender.TrapSender {
+	destination := trapsender.Destination{Retries: 1, Version: "V2c", Timeout: 5 * time.Second, Community: "public"}
+	first, second := destination, destination
+	first.Name, first.Address = "first", "127.0.0.1:65162"
+	second.Name, second.Address = "second", "127.0.0.1:65163"
+	return trapsender.New(trapsender.Configuration{
+		SNMPDestinations:    []trapsender.Destination{first, second},
+		DryRun:              true,
+		DryRunFile:          dryRunFile,
+		DescriptionTemplate: *template.Must(template.New("description").Parse(`{{ len .Alerts }} alerts are firing`)),
+		UserObjects:         make([]trapsender.UserObject, 0),
+	}, newLogger())
+}
+
+func newLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, nil))
+}
+
+func readDryRunFile(t *testing.T, dryRunFile string) []trapsender.RenderedTrap {
+	t.Helper()
+	file, err := os.Open(dryRunFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal("Error while reading dry run file:", err)
+	}
+	defer file.Close()
+
+	traps := []trapsender.RenderedTrap{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		trap := trapsender.RenderedTrap{}
+		if err := json.Unmarshal(scanner.Bytes(), &trap); err != nil {
+			t.Fatal("Error while parsing dry run file:", err)
+		}
+		traps = append(traps, trap)
+	}
+	return traps
+}
@@ -20,10 +20,12 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"sync"
 
 	"github.com/prometheus/exporter-toolkit/web"
 
 	"github.com/maxwo/snmp_notifier/alertparser"
+	"github.com/maxwo/snmp_notifier/alertstore"
 	"github.com/maxwo/snmp_notifier/telemetry"
 	"github.com/maxwo/snmp_notifier/trapsender"
 	"github.com/maxwo/snmp_notifier/types"
@@ -38,8 +40,11 @@ type HTTPServer struct {
 	configuration Configuration
 	alertParser   alertparser.AlertParser
 	trapSender    trapsender.TrapSender
+	alertStore    *alertstore.AlertStore
 	logger        *slog.Logger
 	server        *http.Server
+	reloadChannel chan chan error
+	mutex         sync.RWMutex
 }
 
 // Configuration describes the configuration for serving HTTP requests
@@ -47,13 +52,46 @@ type Configuration struct {
 	ToolKitConfiguration web.FlagConfig
 }
 
-// New creates an HTTPServer instance
-func New(configuration Configuration, alertParser alertparser.AlertParser, trapSender trapsender.TrapSender, logger *slog.Logger) *HTTPServer {
-	return &HTTPServer{configuration, alertParser, trapSender, logger, nil}
+// New creates an HTTPServer instance. The sent alerts are recorded in the alert store, when not nil.
+func New(configuration Configuration, alertParser alertparser.AlertParser, trapSender trapsender.TrapSender, alertStore *alertstore.AlertStore, logger *slog.Logger) *HTTPServer {
+	return &HTTPServer{
+		configuration: configuration,
+		alertParser:   alertParser,
+		trapSender:    trapSender,
+		alertStore:    alertStore,
+		logger:        logger,
+		reloadChannel: make(chan chan error),
+	}
+}
+
+// Reload returns the channel on which configuration reload requests are received
+func (httpServer *HTTPServer) Reload() <-chan chan error {
+	return httpServer.reloadChannel
+}
+
+// Update atomically replaces the alert parser and the trap sender used to handle alerts. The previous trap sender
+// is stopped in the background, once the requests using it are handled.
+func (httpServer *HTTPServer) Update(alertParser alertparser.AlertParser, trapSender trapsender.TrapSender) {
+	httpServer.mutex.Lock()
+	previousTrapSender := httpServer.trapSender
+	httpServer.alertParser = alertParser
+	httpServer.trapSender = trapSender
+	httpServer.mutex.Unlock()
+
+	go previousTrapSender.Stop()
+}
+
+// getAlertParserAndTrapSender returns the current alert parser and trap sender, the trap sender being acquired
+// until the caller releases it
+func (httpServer *HTTPServer) getAlertParserAndTrapSender() (alertparser.AlertParser, trapsender.TrapSender) {
+	httpServer.mutex.RLock()
+	defer httpServer.mutex.RUnlock()
+	httpServer.trapSender.Acquire()
+	return httpServer.alertParser, httpServer.trapSender
 }
 
 // Configure creates and configures the HTTP server
-func (httpServer HTTPServer) Start() error {
+func (httpServer *HTTPServer) Start() error {
 	mux := http.NewServeMux()
 	server := &http.Server{
 		Handler: mux,
@@ -67,6 +105,7 @@ func (httpServer HTTPServer) Start() error {
          <p><a href='/metrics'>SNMP Notifier metrics</a></p>
          <p><a href='/alerts'>SNMP alerts endpoint</a></p>
          <p><a href='/health'>health endpoint</a></p>
+         <form action='/-/reload' method='post'><button type='submit'>Reload the configuration</button></form>
          <h2>Build</h2>
          <pre>` + version.Info() + ` ` + version.BuildContext() + `</pre>
          </body>
@@ -78,6 +117,9 @@ func (httpServer HTTPServer) Start() error {
 
 		defer req.Body.Close()
 
+		alertParser, trapSender := httpServer.getAlertParserAndTrapSender()
+		defer trapSender.Release()
+
 		data := types.AlertsData{}
 		err := json.NewDecoder(req.Body).Decode(&data)
 		if err != nil {
@@ -85,18 +127,58 @@ func (httpServer HTTPServer) Start() error {
 			return
 		}
 
-		alertBucket, err := httpServer.alertParser.Parse(data)
+		alertBucket, err := alertParser.Parse(data)
 		if err != nil {
 			httpServer.errorHandler(w, http.StatusBadRequest, err, &data)
 			return
 		}
 
-		err = httpServer.trapSender.SendAlertTraps(*alertBucket)
+		err = trapSender.SendAlertTraps(*alertBucket)
 		if err != nil {
 			httpServer.errorHandler(w, http.StatusBadGateway, err, &data)
 			return
 		}
 
+		if httpServer.alertStore != nil {
+			httpServer.alertStore.Record(*alertBucket)
+		}
+
+		if trapSender.IsAsynchronous() {
+			w.WriteHeader(http.StatusAccepted)
+			telemetry.RequestTotal.WithLabelValues("202").Inc()
+			return
+		}
+
+		telemetry.RequestTotal.WithLabelValues("200").Inc()
+	})
+
+	mux.HandleFunc("/-/reload", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost && req.Method != http.MethodPut {
+			httpServer.errorHandler(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed, use POST or PUT", req.Method), nil)
+			return
+		}
+
+		httpServer.logger.Info("Handling /-/reload request")
+
+		// the reload goes on when the client gives up, its result is then dropped
+		errorChannel := make(chan error, 1)
+		select {
+		case httpServer.reloadChannel <- errorChannel:
+		case <-req.Context().Done():
+			httpServer.errorHandler(w, http.StatusServiceUnavailable, fmt.Errorf("reload request canceled: %w", req.Context().Err()), nil)
+			return
+		}
+		select {
+		case err := <-errorChannel:
+			if err != nil {
+				httpServer.errorHandler(w, http.StatusInternalServerError, fmt.Errorf("failed to reload configuration: %w", err), nil)
+				return
+			}
+		case <-req.Context().Done():
+			httpServer.errorHandler(w, http.StatusServiceUnavailable, fmt.Errorf("reload request canceled: %w", req.Context().Err()), nil)
+			return
+		}
+
 		telemetry.RequestTotal.WithLabelValues("200").Inc()
 	})
 
@@ -113,7 +195,7 @@ func (httpServer HTTPServer) Start() error {
 	return nil
 }
 
-func (httpServer HTTPServer) Stop() error {
+func (httpServer *HTTPServer) Stop() error {
 	if httpServer.server != nil {
 		httpServer.logger.Error("No server started")
 		return httpServer.server.Close()
@@ -125,7 +207,7 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	io.WriteString(w, "Health: OK\n")
 }
 
-func (httpServer HTTPServer) errorHandler(w http.ResponseWriter, status int, err error, data *types.AlertsData) {
+func (httpServer *HTTPServer) errorHandler(w http.ResponseWriter, status int, err error, data *types.AlertsData) {
 	w.WriteHeader(status)
 
 	response := struct {
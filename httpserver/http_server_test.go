@@ -16,6 +16,7 @@ package httpserver
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -30,6 +31,7 @@ import (
 	"github.com/k-sone/snmpgo"
 	"github.com/maxwo/snmp_notifier/alertparser"
 	"github.com/maxwo/snmp_notifier/trapsender"
+	"github.com/maxwo/snmp_notifier/types"
 
 	testutils "github.com/maxwo/snmp_notifier/test"
 
@@ -113,10 +115,145 @@ func TestCallHealthURI(t *testing.T) {
 	expectNoSNMPTrap(t, trapChannel)
 }
 
+func TestReloadURI(t *testing.T) {
+	expectReloadHTTPStatus(t, "POST", nil, 200)
+}
+
+func TestFailedReloadURI(t *testing.T) {
+	expectReloadHTTPStatus(t, "POST", errors.New("invalid configuration"), 500)
+}
+
+func TestReloadURIWithWrongVerb(t *testing.T) {
+	expectReloadHTTPStatus(t, "GET", nil, 405)
+}
+
+func TestUpdate(t *testing.T) {
+	port, server, trapChannel, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("msg", "Error while starting SNMP server:", "err", err)
+	}
+	defer server.Close()
+
+	httpserver, notifierPort := launchHTTPServer(t, 123)
+	defer httpserver.Stop()
+
+	alertParser, trapSender := newAlertParserAndTrapSender(t, *port)
+	httpserver.Update(alertParser, trapSender)
+
+	expectHTTPStatusFromServer(t, notifierPort, "POST", "/alerts", "test_mixed_alerts.json", 200)
+	expectSNMPTraps(t, "test_mixed_traps.json", trapChannel)
+}
+
+func TestUpdateWaitsForInFlightRequests(t *testing.T) {
+	port, server, trapChannel, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("msg", "Error while starting SNMP server:", "err", err)
+	}
+	defer server.Close()
+
+	httpserver, notifierPort := launchHTTPServer(t, 123)
+	defer httpserver.Stop()
+
+	alertParser, trapSender := newAlertParserAndQueuedTrapSender(t, *port, 10)
+	httpserver.Update(alertParser, trapSender)
+
+	// a request handled while the configuration is reloaded
+	alertParser, trapSender = httpserver.getAlertParserAndTrapSender()
+	httpserver.Update(newAlertParserAndQueuedTrapSender(t, *port, 10))
+	time.Sleep(50 * time.Millisecond)
+
+	alertsByteData, err := os.ReadFile("test_mixed_alerts.json")
+	if err != nil {
+		t.Fatal("Error while reading alert file:", err)
+	}
+	data := types.AlertsData{}
+	if err := json.Unmarshal(alertsByteData, &data); err != nil {
+		t.Fatal("Error while parsing alert file:", err)
+	}
+	alertBucket, err := alertParser.Parse(data)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err := trapSender.SendAlertTraps(*alertBucket); err != nil {
+		t.Error("the previous trap sender should not be stopped while used", err)
+	}
+	expectSNMPTraps(t, "test_mixed_traps.json", trapChannel)
+
+	trapSender.Release()
+	time.Sleep(50 * time.Millisecond)
+	if err := trapSender.SendAlertTraps(*alertBucket); err == nil {
+		t.Error("the previous trap sender should be stopped once released")
+	}
+
+	expectHTTPStatusFromServer(t, notifierPort, "POST", "/alerts", "test_mixed_alerts.json", 202)
+	expectSNMPTraps(t, "test_mixed_traps.json", trapChannel)
+}
+
+func TestCanceledReload(t *testing.T) {
+	httpserver, notifierPort := launchHTTPServer(t, 123)
+	defer httpserver.Stop()
+
+	// nothing handles the reload requests
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+	if _, err := client.Post(fmt.Sprintf("http://127.0.0.1:%d/-/reload", notifierPort), "application/json", nil); err == nil {
+		t.Fatal("the reload request should time out")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	select {
+	case <-httpserver.Reload():
+		t.Error("a canceled reload request should be given up")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestQueuedAlertNotification(t *testing.T) {
+	port, server, trapChannel, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("msg", "Error while starting SNMP server:", "err", err)
+	}
+	defer server.Close()
+
+	httpserver, notifierPort := launchHTTPServer(t, 123)
+	defer httpserver.Stop()
+
+	alertParser, trapSender := newAlertParserAndQueuedTrapSender(t, *port, 10)
+	httpserver.Update(alertParser, trapSender)
+
+	expectHTTPStatusFromServer(t, notifierPort, "POST", "/alerts", "test_mixed_alerts.json", 202)
+	expectSNMPTraps(t, "test_mixed_traps.json", trapChannel)
+}
+
+func TestQueuedAlertNotificationToBadSNMPDestination(t *testing.T) {
+	httpserver, notifierPort := launchHTTPServer(t, 123)
+	defer httpserver.Stop()
+
+	alertParser, trapSender := newAlertParserAndQueuedTrapSender(t, 123, 10)
+	httpserver.Update(alertParser, trapSender)
+
+	expectHTTPStatusFromServer(t, notifierPort, "POST", "/alerts", "test_mixed_alerts.json", 202)
+}
+
+func expectReloadHTTPStatus(t *testing.T, verb string, reloadError error, status int) {
+	httpserver, notifierPort := launchHTTPServer(t, 123)
+	defer httpserver.Stop()
+
+	go func() {
+		errorChannel := <-httpserver.Reload()
+		errorChannel <- reloadError
+	}()
+
+	expectHTTPStatusFromServer(t, notifierPort, verb, "/-/reload", "test_no_body.json", status)
+}
+
 func expectHTTPStatus(t *testing.T, snmpDestinationPort int32, verb string, uri string, body string, status int) {
 	httpserver, notifierPort := launchHTTPServer(t, snmpDestinationPort)
 	defer httpserver.Stop()
 
+	expectHTTPStatusFromServer(t, notifierPort, verb, uri, body, status)
+}
+
+func expectHTTPStatusFromServer(t *testing.T, notifierPort int, verb string, uri string, body string, status int) {
 	t.Log("Testing with file", body)
 	alertsByteData, err := os.ReadFile(body)
 	if err != nil {
@@ -151,52 +288,15 @@ func expectHTTPStatus(t *testing.T, snmpDestinationPort int32, verb string, uri
 func launchHTTPServer(t *testing.T, port int32) (*HTTPServer, int) {
 	notfierRandomPort := 10000 + rand.Intn(10000)
 
-	snmpDestination := fmt.Sprintf("127.0.0.1:%d", port)
 	notifierAddress := fmt.Sprintf(":%d", notfierRandomPort)
 
-	alertParserConfiguration := alertparser.Configuration{
-		TrapDefaultOID:            "1.2.3",
-		TrapOIDLabel:              "oid",
-		DefaultSeverity:           "critical",
-		Severities:                strings.Split("critical,warning,info", ","),
-		SeverityLabel:             "severity",
-		TrapDefaultObjectsBaseOID: "1.7.8",
-		TrapUserObjectsBaseOID:    "1.7.9",
-	}
-	alertParser := alertparser.New(alertParserConfiguration, slog.New(slog.NewTextHandler(os.Stdout, nil)))
-
-	descriptionTemplate, err := template.New("description").Parse(dummyDescriptionTemplate)
-	if err != nil {
-		t.Fatal("Error while building template")
-	}
+	alertParser, trapSender := newAlertParserAndTrapSender(t, port)
 
 	var falseValue = false
 	var emptyString = ""
 
-	trapSenderConfiguration := trapsender.Configuration{
-		SNMPDestination:            []string{snmpDestination},
-		SNMPRetries:                1,
-		SNMPVersion:                "V2c",
-		SNMPTimeout:                5 * time.Second,
-		SNMPCommunity:              "public",
-		SNMPAuthenticationEnabled:  false,
-		SNMPAuthenticationProtocol: "",
-		SNMPAuthenticationUsername: "",
-		SNMPAuthenticationPassword: "",
-		SNMPPrivateEnabled:         false,
-		SNMPPrivateProtocol:        "",
-		SNMPPrivatePassword:        "",
-		SNMPSecurityEngineID:       "",
-		SNMPContextEngineID:        "",
-		SNMPContextName:            "",
-		DescriptionTemplate:        *descriptionTemplate,
-		UserObjects:                make([]trapsender.UserObject, 0),
-	}
-
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-	trapSender := trapsender.New(trapSenderConfiguration, logger)
-
 	httpServerConfiguration := Configuration{
 		web.FlagConfig{
 			WebListenAddresses: &[]string{notifierAddress},
@@ -204,7 +304,7 @@ func launchHTTPServer(t *testing.T, port int32) (*HTTPServer, int) {
 			WebConfigFile:      &emptyString,
 		},
 	}
-	httpServer := New(httpServerConfiguration, alertParser, trapSender, logger)
+	httpServer := New(httpServerConfiguration, alertParser, trapSender, nil, logger)
 	go func() {
 		if err := httpServer.Start(); err != nil {
 			t.Error("err", err)
@@ -215,6 +315,61 @@ func launchHTTPServer(t *testing.T, port int32) (*HTTPServer, int) {
 	return httpServer, notfierRandomPort
 }
 
+func newAlertParserAndTrapSender(t *testing.T, port int32) (alertparser.AlertParser, trapsender.TrapSender) {
+	return newAlertParserAndQueuedTrapSender(t, port, 0)
+}
+
+func newAlertParserAndQueuedTrapSender(t *testing.T, port int32, queueSize int) (alertparser.AlertParser, trapsender.TrapSender) {
+	snmpDestination := fmt.Sprintf("127.0.0.1:%d", port)
+
+	alertParserConfiguration := alertparser.Configuration{
+		TrapDefaultOID:            "1.2.3",
+		TrapOIDLabel:              "oid",
+		DefaultSeverity:           "critical",
+		Severities:                strings.Split("critical,warning,info", ","),
+		SeverityLabel:             "severity",
+		TrapDefaultObjectsBaseOID: "1.7.8",
+		TrapUserObjectsBaseOID:    "1.7.9",
+	}
+	alertParser := alertparser.New(alertParserConfiguration, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	descriptionTemplate, err := template.New("description").Parse(dummyDescriptionTemplate)
+	if err != nil {
+		t.Fatal("Error while building template")
+	}
+
+	trapSenderConfiguration := trapsender.Configuration{
+		SNMPDestinations: []trapsender.Destination{
+			{
+				Address:                snmpDestination,
+				Retries:                1,
+				Version:                "V2c",
+				Timeout:                5 * time.Second,
+				Community:              "public",
+				AuthenticationEnabled:  false,
+				AuthenticationProtocol: "",
+				AuthenticationUsername: "",
+				AuthenticationPassword: "",
+				PrivateEnabled:         false,
+				PrivateProtocol:        "",
+				PrivatePassword:        "",
+				SecurityEngineID:       "",
+				ContextEngineID:        "",
+				ContextName:            "",
+			},
+		},
+		DescriptionTemplate: *descriptionTemplate,
+		UserObjects:         make([]trapsender.UserObject, 0),
+		QueueSize:           queueSize,
+		QueueWorkers:        1,
+		QueueRetryBackoff:   time.Second,
+	}
+
+	trapSender := trapsender.New(trapSenderConfiguration, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	return alertParser, trapSender
+}
+
 func expectNoSNMPTrap(t *testing.T, trapChannel chan *snmpgo.TrapRequest) {
 	receivedTraps := testutils.ReadTraps(trapChannel)
 
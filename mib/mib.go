@@ -0,0 +1,411 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mib
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maxwo/snmp_notifier/commons"
+	"github.com/maxwo/snmp_notifier/trapsender"
+)
+
+const notifierModuleName = "SNMP-NOTIFIER-MIB"
+
+var (
+	moduleNameRegexp = regexp.MustCompile("^[A-Z][A-Za-z0-9]*(-[A-Za-z0-9]+)*$")
+	descriptorRegexp = regexp.MustCompile("^[a-z][A-Za-z0-9]{0,63}$")
+
+	// OIDs defined by SNMPv2-SMI and SNMP-NOTIFIER-MIB, from the most specific to the least specific
+	knownOIDs = []knownOID{
+		{"snmpNotifierAlertsUserObjects", "1.3.6.1.4.1.98789.3", notifierModuleName},
+		{"snmpNotifierAlertsObjects", "1.3.6.1.4.1.98789.2", notifierModuleName},
+		{"snmpNotifier", "1.3.6.1.4.1.98789", notifierModuleName},
+		{"enterprises", "1.3.6.1.4.1", "SNMPv2-SMI"},
+		// the roots of the OID tree are built into ASN.1, and not imported
+		{"iso", "1", ""},
+		{"ccitt", "0", ""},
+		{"joint-iso-ccitt", "2", ""},
+	}
+
+	// notifications defined by SNMP-NOTIFIER-MIB, which the generated module may not define again
+	notifierNotifications = []knownOID{
+		{"snmpNotifierDefaultTrap", "1.3.6.1.4.1.98789.1", notifierModuleName},
+		{"snmpNotifierHeartbeatTrap", "1.3.6.1.4.1.98789.5", notifierModuleName},
+	}
+
+	// default objects of SNMP-NOTIFIER-MIB, by sub-OID
+	defaultObjects = []defaultObject{
+		{1, "AlertId", trapsender.StringObjectType, "The ID of the SNMP notifier alert."},
+		{2, "AlertSeverity", trapsender.StringObjectType, "The severity of the SNMP notifier alert."},
+		{3, "AlertDescription", trapsender.StringObjectType, "The description of the SNMP notifier alert."},
+		{4, "AlertGroupKey", trapsender.StringObjectType, "The Alertmanager group key of the SNMP notifier alert."},
+		{5, "AlertSeverityLevel", trapsender.IntegerObjectType, "The numeric level of the severity of the SNMP notifier alert."},
+	}
+
+	// objects of SNMP-NOTIFIER-MIB sent when the alert groups are split into several traps
+	partObjects = []defaultObject{
+		{6, "AlertPartIndex", trapsender.IntegerObjectType, "The index of the part of the SNMP notifier alert, when its alerts are split into several traps."},
+		{7, "AlertPartCount", trapsender.IntegerObjectType, "The number of parts of the SNMP notifier alert, when its alerts are split into several traps."},
+	}
+
+	// SMIv2 syntaxes of the SNMP types, and the module defining them
+	syntaxes = map[string]syntax{
+		trapsender.StringObjectType:    {"DisplayString", "SNMPv2-TC"},
+		trapsender.IntegerObjectType:   {"Integer32", "SNMPv2-SMI"},
+		trapsender.Counter32ObjectType: {"Counter32", "SNMPv2-SMI"},
+		trapsender.Gauge32ObjectType:   {"Gauge32", "SNMPv2-SMI"},
+		trapsender.Counter64ObjectType: {"Counter64", "SNMPv2-SMI"},
+		trapsender.TimeTicksObjectType: {"TimeTicks", "SNMPv2-SMI"},
+		trapsender.IPAddressObjectType: {"IpAddress", "SNMPv2-SMI"},
+		trapsender.OIDObjectType:       {"OBJECT IDENTIFIER", ""},
+	}
+)
+
+type knownOID struct {
+	name   string
+	oid    string
+	module string
+}
+
+type defaultObject struct {
+	subOID      int
+	name        string
+	objectType  string
+	description string
+}
+
+type syntax struct {
+	name   string
+	module string
+}
+
+// Configuration describes the MIB module to generate from the SNMP notifier configuration
+type Configuration struct {
+	ModuleName            string
+	ModuleOID             string
+	LastUpdated           time.Time
+	TrapOID               string
+	ResolutionTrapOID     *string
+	DefaultObjectsBaseOID string
+	UserObjectsBaseOID    string
+	DescriptionType       string
+	UserObjects           []trapsender.UserObject
+	// SplitAlerts adds the part index and count objects to the notifications
+	SplitAlerts bool
+}
+
+// generator accumulates the definitions of the module and the symbols they import
+type generator struct {
+	configuration Configuration
+	prefix        string
+	imports       map[string][]string
+	names         map[string]bool
+	// nodes are the names of the intermediate OBJECT IDENTIFIER nodes, by OID
+	nodes       map[string]string
+	identity    strings.Builder
+	definitions strings.Builder
+}
+
+// IsDescriptor checks if a given string is a valid SMIv2 descriptor, i.e. an object name
+func IsDescriptor(name string) bool {
+	return descriptorRegexp.MatchString(name)
+}
+
+// Generate returns an SMIv2 module extending SNMP-NOTIFIER-MIB with the user objects,
+// and the notifications sending them
+func Generate(configuration Configuration) (string, error) {
+	if !moduleNameRegexp.MatchString(configuration.ModuleName) || configuration.ModuleName == notifierModuleName {
+		return "", fmt.Errorf("invalid MIB module name: %s", configuration.ModuleName)
+	}
+	for _, oid := range []string{configuration.ModuleOID, configuration.TrapOID, configuration.DefaultObjectsBaseOID, configuration.UserObjectsBaseOID} {
+		if !commons.IsOID(oid) {
+			return "", fmt.Errorf("invalid OID provided: \"%s\"", oid)
+		}
+	}
+
+	generator := &generator{
+		configuration: configuration,
+		prefix:        modulePrefix(configuration.ModuleName),
+		imports:       map[string][]string{},
+		names:         notifierNames(),
+		nodes:         map[string]string{},
+	}
+	if err := generator.generate(); err != nil {
+		return "", err
+	}
+
+	imports := []string{}
+	for _, importedModule := range []string{"SNMPv2-SMI", "SNMPv2-TC", notifierModuleName} {
+		if symbols, found := generator.imports[importedModule]; found {
+			imports = append(imports, fmt.Sprintf("   %s\n      FROM %s", strings.Join(symbols, ", "), importedModule))
+		}
+	}
+
+	module := &strings.Builder{}
+	fmt.Fprintf(module, "%s DEFINITIONS ::= BEGIN\n\nIMPORTS\n%s;\n", configuration.ModuleName, strings.Join(imports, "\n"))
+	// the MODULE-IDENTITY must be the first definition of the module
+	module.WriteString(generator.identity.String())
+	module.WriteString(generator.definitions.String())
+	module.WriteString("\nEND\n")
+	return module.String(), nil
+}
+
+func (generator *generator) generate() error {
+	configuration := generator.configuration
+
+	generator.addImport("SNMPv2-SMI", "MODULE-IDENTITY")
+
+	identityName := generator.prefix + "MIB"
+	if err := generator.reserveName(identityName); err != nil {
+		return err
+	}
+	lastUpdated := configuration.LastUpdated.UTC().Format("200601021504Z")
+	moduleOID := generator.oidValue(configuration.ModuleOID)
+	fmt.Fprintf(&generator.identity, `
+%s MODULE-IDENTITY
+   LAST-UPDATED "%s"
+   ORGANIZATION "SNMP Notifier"
+   CONTACT-INFO
+      "https://github.com/maxwo/snmp_notifier/"
+   DESCRIPTION
+      "This MIB describes the user objects of the SNMP notifier traps,
+      and the notifications carrying them."
+   REVISION
+      "%s"
+   DESCRIPTION
+      "Generated by the snmp_notifier generate-mib command."
+::= %s
+`, identityName, lastUpdated, lastUpdated, moduleOID)
+
+	objects := []string{}
+	notifierObjects := defaultObjects
+	if configuration.SplitAlerts {
+		notifierObjects = slices.Concat(defaultObjects, partObjects)
+	}
+
+	if configuration.DefaultObjectsBaseOID == "1.3.6.1.4.1.98789.2" {
+		for _, object := range notifierObjects {
+			name := "snmpNotifier" + object.name
+			generator.addImport(notifierModuleName, name)
+			objects = append(objects, name)
+		}
+	} else {
+		// the default objects are moved, they are defined again under their base OID
+		defaultObjectsParent := generator.parentName(configuration.DefaultObjectsBaseOID, "AlertsObjects")
+		for _, object := range notifierObjects {
+			objectType := object.objectType
+			if object.subOID == 3 && configuration.DescriptionType != "" {
+				objectType = configuration.DescriptionType
+			}
+			name := generator.prefix + object.name
+			if err := generator.addObject(name, objectType, object.description, defaultObjectsParent, object.subOID); err != nil {
+				return err
+			}
+			objects = append(objects, name)
+		}
+	}
+
+	for _, userObject := range configuration.UserObjects {
+		if configuration.UserObjectsBaseOID == configuration.DefaultObjectsBaseOID && userObject.SubOID <= len(notifierObjects) {
+			return fmt.Errorf("user object %d overlaps the default object of the same sub-OID", userObject.SubOID)
+		}
+		name := userObject.Name
+		if name == "" {
+			name = fmt.Sprintf("%sObject%d", generator.prefix, userObject.SubOID)
+		}
+		description := userObject.Description
+		if description == "" {
+			description = fmt.Sprintf("The user object %d of the SNMP notifier alert, rendered from the %s template.", userObject.SubOID, userObject.ContentTemplate.Name())
+		}
+		userObjectsParent := generator.parentName(configuration.UserObjectsBaseOID, "UserObjects")
+		if err := generator.addObject(name, userObject.Type, description, userObjectsParent, userObject.SubOID); err != nil {
+			return err
+		}
+		objects = append(objects, name)
+	}
+
+	if err := generator.addNotification(generator.prefix+"Trap", "The SNMP notifier notification, with the user objects.", configuration.TrapOID, "--trap.default-oid", 1, objects); err != nil {
+		return err
+	}
+	if configuration.ResolutionTrapOID != nil && *configuration.ResolutionTrapOID != configuration.TrapOID {
+		if !commons.IsOID(*configuration.ResolutionTrapOID) {
+			return fmt.Errorf("invalid OID provided: \"%s\"", *configuration.ResolutionTrapOID)
+		}
+		if err := generator.addNotification(generator.prefix+"ResolutionTrap", "The SNMP notifier notification of the resolved alerts, with the user objects.", *configuration.ResolutionTrapOID, "--trap.resolution-default-oid", 2, objects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notificationsName returns the name of the node of the notifications defined under the module identity
+func (generator *generator) notificationsName() string {
+	name := generator.prefix + "Notifications"
+	if !generator.names[name] {
+		generator.names[name] = true
+		fmt.Fprintf(&generator.definitions, "\n%s OBJECT IDENTIFIER ::= { %sMIB 0 }\n", name, generator.prefix)
+	}
+	return name
+}
+
+// parentName returns the name of a base OID, and defines it unless it is a known one
+func (generator *generator) parentName(oid string, suffix string) string {
+	for _, known := range knownOIDs {
+		if known.oid == oid {
+			generator.addImport(known.module, known.name)
+			return known.name
+		}
+	}
+	name := generator.prefix + suffix
+	if !generator.names[name] {
+		generator.names[name] = true
+		fmt.Fprintf(&generator.definitions, "\n%s OBJECT IDENTIFIER ::= %s\n", name, generator.oidValue(oid))
+	}
+	return name
+}
+
+func (generator *generator) addObject(name string, objectType string, description string, parent string, subOID int) error {
+	if err := generator.reserveName(name); err != nil {
+		return err
+	}
+	generator.addImport("SNMPv2-SMI", "OBJECT-TYPE")
+	if objectType == "" {
+		objectType = trapsender.StringObjectType
+	}
+	objectSyntax := syntaxes[objectType]
+	if objectSyntax.module != "" {
+		generator.addImport(objectSyntax.module, objectSyntax.name)
+	}
+	fmt.Fprintf(&generator.definitions, `
+%s OBJECT-TYPE
+   SYNTAX      %s
+   MAX-ACCESS  accessible-for-notify
+   STATUS      current
+   DESCRIPTION "%s"
+::= { %s %d }
+`, name, objectSyntax.name, quote(description), parent, subOID)
+	return nil
+}
+
+// addNotification defines a notification carrying the objects. A trap OID already defined by SNMP-NOTIFIER-MIB may
+// not be defined twice when both modules are loaded, the notification is then defined under the module identity,
+// and the flag sending it is given in a comment.
+func (generator *generator) addNotification(name string, description string, oid string, flag string, index int, objects []string) error {
+	if err := generator.reserveName(name); err != nil {
+		return err
+	}
+	generator.addImport("SNMPv2-SMI", "NOTIFICATION-TYPE")
+	oidValue := ""
+	for _, notification := range notifierNotifications {
+		if notification.oid == oid {
+			oidValue = fmt.Sprintf("{ %s %d }", generator.notificationsName(), index)
+			fmt.Fprintf(&generator.definitions, "\n-- %s of %s does not list the user objects, use %s=%s.0.%d to send %s instead\n",
+				notification.name, notification.module, flag, generator.configuration.ModuleOID, index, name)
+		}
+	}
+	if oidValue == "" {
+		oidValue = generator.oidValue(oid)
+	}
+	fmt.Fprintf(&generator.definitions, `
+%s NOTIFICATION-TYPE
+   OBJECTS {
+      %s
+   }
+   STATUS      current
+   DESCRIPTION "%s"
+::= %s
+`, name, strings.Join(objects, ",\n      "), quote(description), oidValue)
+	return nil
+}
+
+func (generator *generator) reserveName(name string) error {
+	if !IsDescriptor(name) {
+		return fmt.Errorf("invalid MIB object name: %s", name)
+	}
+	if generator.names[name] {
+		return fmt.Errorf("MIB object name defined twice: %s", name)
+	}
+	generator.names[name] = true
+	return nil
+}
+
+func (generator *generator) addImport(module string, symbol string) {
+	if !slices.Contains(generator.imports[module], symbol) {
+		generator.imports[module] = append(generator.imports[module], symbol)
+	}
+}
+
+// oidValue writes an OID relative to its parent, e.g. { snmpNotifier 1 }. The parents between the most specific
+// known OID and the OID are defined as intermediate nodes, as SMIv2 values have a single sub-identifier.
+func (generator *generator) oidValue(oid string) string {
+	parent, subIDs := "", strings.Split(oid, ".")
+	for _, known := range knownOIDs {
+		if suffix, found := strings.CutPrefix(oid, known.oid+"."); found {
+			if known.module != "" {
+				generator.addImport(known.module, known.name)
+			}
+			parent, subIDs = known.name, strings.Split(suffix, ".")
+			break
+		}
+	}
+	if parent == "" {
+		return fmt.Sprintf("{ %s }", strings.Join(subIDs, " "))
+	}
+
+	nodeOID := strings.TrimSuffix(oid, "."+strings.Join(subIDs, "."))
+	for index, subID := range subIDs[:len(subIDs)-1] {
+		nodeOID += "." + subID
+		name, found := generator.nodes[nodeOID]
+		if !found {
+			// e.g. snmpNotifierUserNode0x2 for { snmpNotifier 0 2 }
+			name = generator.prefix + "Node" + strings.Join(subIDs[:index+1], "x")
+			generator.nodes[nodeOID] = name
+			generator.names[name] = true
+			fmt.Fprintf(&generator.definitions, "\n%s OBJECT IDENTIFIER ::= { %s %s }\n", name, parent, subID)
+		}
+		parent = name
+	}
+	return fmt.Sprintf("{ %s %s }", parent, subIDs[len(subIDs)-1])
+}
+
+// notifierNames returns the names defined by SNMP-NOTIFIER-MIB, which the generated module may not redefine
+func notifierNames() map[string]bool {
+	names := map[string]bool{}
+	for _, known := range slices.Concat(knownOIDs, notifierNotifications) {
+		names[known.name] = true
+	}
+	for _, object := range slices.Concat(defaultObjects, partObjects) {
+		names["snmpNotifier"+object.name] = true
+	}
+	return names
+}
+
+// modulePrefix derives the prefix of the names of a module from its name, e.g. snmpNotifierUser for SNMP-NOTIFIER-USER-MIB
+func modulePrefix(moduleName string) string {
+	words := strings.Split(strings.TrimSuffix(moduleName, "-MIB"), "-")
+	prefix := strings.ToLower(words[0])
+	for _, word := range words[1:] {
+		prefix += strings.ToUpper(word[:1]) + strings.ToLower(word[1:])
+	}
+	return prefix
+}
+
+// quote makes a text suitable for a quoted MIB string, which may not contain double quotes
+func quote(text string) string {
+	return strings.ReplaceAll(text, "\"", "'")
+}
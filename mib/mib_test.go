@@ -0,0 +1,255 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mib
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/go-test/deep"
+
+	"github.com/maxwo/snmp_notifier/commons"
+	"github.com/maxwo/snmp_notifier/trapsender"
+)
+
+var resolutionTrapOIDForTest = "1.3.6.1.4.1.98789.0.2"
+
+func TestGenerateUserMIB(t *testing.T) {
+	module, err := Generate(userMIBConfiguration())
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expected, err := os.ReadFile("test_user_mib.my")
+	if err != nil {
+		t.Fatal("Error while reading MIB file:", err)
+	}
+	if diff := deep.Equal(module, string(expected)); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestGenerateMIBWithSplitAlerts(t *testing.T) {
+	configuration := userMIBConfiguration()
+	configuration.SplitAlerts = true
+
+	module, err := Generate(configuration)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	for _, expected := range []string{
+		"snmpNotifierAlertSeverityLevel, snmpNotifierAlertPartIndex, snmpNotifierAlertPartCount",
+		"      snmpNotifierAlertPartIndex,\n      snmpNotifierAlertPartCount,\n      snmpNotifierUserObject4",
+	} {
+		if !strings.Contains(module, expected) {
+			t.Errorf("the module should contain %q, got %s", expected, module)
+		}
+	}
+}
+
+func TestGeneratedMIBResolvesWithNotifierMIB(t *testing.T) {
+	configuration := userMIBConfiguration()
+	configuration.ModuleName = "ACME-ALERTS-MIB"
+	configuration.DefaultObjectsBaseOID = "1.3.6.1.4.1.555.2"
+	configuration.UserObjectsBaseOID = "1.3.6.1.4.1.555.3"
+	configuration.TrapOID = "1.3.6.1.4.1.555.0.1"
+	configuration.SplitAlerts = true
+
+	module, err := Generate(configuration)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	resolver := loadWithNotifierMIB(t, configuration.ModuleName, module)
+	for name, expectedOID := range map[string]string{
+		"ACME-ALERTS-MIB::acmeAlertsMIB":                "1.3.6.1.4.1.98789.100",
+		"ACME-ALERTS-MIB::acmeAlertsAlertId":            "1.3.6.1.4.1.555.2.1",
+		"ACME-ALERTS-MIB::acmeAlertsAlertSeverityLevel": "1.3.6.1.4.1.555.2.5",
+		"ACME-ALERTS-MIB::acmeAlertsAlertPartCount":     "1.3.6.1.4.1.555.2.7",
+		"ACME-ALERTS-MIB::acmeAlertsObject4":            "1.3.6.1.4.1.555.3.4",
+		"ACME-ALERTS-MIB::snmpNotifierAlertCount":       "1.3.6.1.4.1.555.3.5",
+		"ACME-ALERTS-MIB::acmeAlertsTrap":               "1.3.6.1.4.1.555.0.1",
+		"ACME-ALERTS-MIB::acmeAlertsResolutionTrap":     "1.3.6.1.4.1.98789.0.2",
+	} {
+		if oid, err := resolver.ResolveOID(name); err != nil || oid != expectedOID {
+			t.Errorf("%s should be resolved as %s, got %s, %v", name, expectedOID, oid, err)
+		}
+	}
+}
+
+func TestGeneratedMIBDoesNotRedefineNotifierOIDs(t *testing.T) {
+	configuration := userMIBConfiguration()
+	module, err := Generate(configuration)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	notifierMIB, err := os.ReadFile("../mibs/SNMP-NOTIFIER-MIB.my")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := loadWithNotifierMIB(t, configuration.ModuleName, module)
+
+	// the default trap OID is the one of snmpNotifierDefaultTrap, the notification is defined under the module identity
+	if oid, err := resolver.ResolveOID("SNMP-NOTIFIER-USER-MIB::snmpNotifierUserTrap"); err != nil || oid != "1.3.6.1.4.1.98789.100.0.1" {
+		t.Errorf("the notification of the default trap OID should be defined under the module identity, got %s, %v", oid, err)
+	}
+
+	names := map[string]string{}
+	for moduleName, content := range map[string]string{notifierModuleName: string(notifierMIB), configuration.ModuleName: module} {
+		for _, value := range oidValueRegexp.FindAllStringSubmatch(content, -1) {
+			if len(strings.Fields(value[1])) != 2 {
+				t.Errorf("the OID value of %s should have a parent and a sub-identifier, got { %s }", moduleName, value[1])
+			}
+		}
+		for _, definition := range definitionRegexp.FindAllStringSubmatch(content, -1) {
+			name := moduleName + "::" + definition[1]
+			oid, err := resolver.ResolveOID(name)
+			if err != nil {
+				t.Errorf("%s should be resolved, got %v", name, err)
+				continue
+			}
+			if other, found := names[oid]; found {
+				t.Errorf("%s and %s are both defined as %s", other, name, oid)
+			}
+			names[oid] = name
+		}
+	}
+	if _, found := names["1.3.6.1.4.1.98789.0.2"]; !found || len(names) < 30 {
+		t.Error("the definitions of both modules should be checked", names)
+	}
+}
+
+func TestGeneratedMIBImportsAreUsed(t *testing.T) {
+	var tests = map[string]func(*Configuration){
+		"user objects": func(configuration *Configuration) {},
+		"no user objects": func(configuration *Configuration) {
+			configuration.UserObjects = nil
+		},
+		"moved default objects": func(configuration *Configuration) {
+			configuration.DefaultObjectsBaseOID = "1.3.6.1.4.1.555.2"
+		},
+	}
+	for name, modify := range tests {
+		configuration := userMIBConfiguration()
+		modify(&configuration)
+		module, err := Generate(configuration)
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+
+		imports, definitions, _ := strings.Cut(module, ";\n")
+		imports = fromRegexp.ReplaceAllString(strings.SplitN(imports, "IMPORTS", 2)[1], "")
+		for _, symbol := range importedSymbolRegexp.FindAllString(imports, -1) {
+			if !regexp.MustCompile(`\b` + regexp.QuoteMeta(symbol) + `\b`).MatchString(definitions) {
+				t.Errorf("%s is imported but not used, test %s", symbol, name)
+			}
+		}
+	}
+}
+
+func TestGenerateMIBErrors(t *testing.T) {
+	var tests = map[string]func(*Configuration){
+		"invalid module name": func(configuration *Configuration) {
+			configuration.ModuleName = "snmp-notifier-user-mib"
+		},
+		"notifier module name": func(configuration *Configuration) {
+			configuration.ModuleName = "SNMP-NOTIFIER-MIB"
+		},
+		"invalid module OID": func(configuration *Configuration) {
+			configuration.ModuleOID = "1.a"
+		},
+		"invalid object name": func(configuration *Configuration) {
+			configuration.UserObjects[0].Name = "Invalid-Name"
+		},
+		"name of the notifier MIB": func(configuration *Configuration) {
+			configuration.UserObjects[0].Name = "snmpNotifierAlertId"
+		},
+		"name defined twice": func(configuration *Configuration) {
+			configuration.UserObjects[0].Name = "snmpNotifierAlertCount"
+		},
+		"user object overlapping a default object": func(configuration *Configuration) {
+			configuration.UserObjectsBaseOID = configuration.DefaultObjectsBaseOID
+		},
+		"user object overlapping a part object": func(configuration *Configuration) {
+			configuration.DefaultObjectsBaseOID = "1.3.6.1.4.1.555.2"
+			configuration.UserObjectsBaseOID = "1.3.6.1.4.1.555.2"
+			configuration.UserObjects[0].SubOID = 7
+			configuration.UserObjects[1].SubOID = 8
+			configuration.SplitAlerts = true
+		},
+	}
+	for name, modify := range tests {
+		configuration := userMIBConfiguration()
+		modify(&configuration)
+		if _, err := Generate(configuration); err == nil {
+			t.Error("an error was expected", "test", name)
+		}
+	}
+}
+
+var (
+	definitionRegexp     = regexp.MustCompile(`(?m)^([a-z][A-Za-z0-9]*)\s+(?:OBJECT-TYPE|NOTIFICATION-TYPE|MODULE-IDENTITY|OBJECT IDENTIFIER ::=)`)
+	oidValueRegexp       = regexp.MustCompile(`::=\s*\{([^}]*)\}`)
+	fromRegexp           = regexp.MustCompile(`FROM\s+[A-Za-z0-9-]+`)
+	importedSymbolRegexp = regexp.MustCompile(`[A-Za-z][A-Za-z0-9-]*`)
+)
+
+// loadWithNotifierMIB loads a generated module along with SNMP-NOTIFIER-MIB
+func loadWithNotifierMIB(t *testing.T, moduleName string, module string) *commons.OIDResolver {
+	directory := t.TempDir()
+	notifierMIB, err := os.ReadFile("../mibs/SNMP-NOTIFIER-MIB.my")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(directory, "SNMP-NOTIFIER-MIB.my"), notifierMIB, 0o644)
+	os.WriteFile(filepath.Join(directory, moduleName+".my"), []byte(module), 0o644)
+
+	resolver, err := commons.LoadMIBs(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolver
+}
+
+func userMIBConfiguration() Configuration {
+	return Configuration{
+		ModuleName:            "SNMP-NOTIFIER-USER-MIB",
+		ModuleOID:             "1.3.6.1.4.1.98789.100",
+		LastUpdated:           time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		TrapOID:               "1.3.6.1.4.1.98789.1",
+		ResolutionTrapOID:     &resolutionTrapOIDForTest,
+		DefaultObjectsBaseOID: "1.3.6.1.4.1.98789.2",
+		UserObjectsBaseOID:    "1.3.6.1.4.1.98789.3",
+		DescriptionType:       trapsender.StringObjectType,
+		UserObjects: []trapsender.UserObject{
+			{
+				SubOID:          4,
+				Type:            trapsender.StringObjectType,
+				ContentTemplate: *template.New("runbook.tpl"),
+			},
+			{
+				SubOID:          5,
+				Type:            trapsender.Gauge32ObjectType,
+				ContentTemplate: *template.New("count.tpl"),
+				Name:            "snmpNotifierAlertCount",
+				Description:     "The number of \"alerts\" of the trap.",
+			},
+		},
+	}
+}
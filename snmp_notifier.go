@@ -14,37 +14,226 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/maxwo/snmp_notifier/alertparser"
+	"github.com/maxwo/snmp_notifier/alertstore"
 	"github.com/maxwo/snmp_notifier/configuration"
+	"github.com/maxwo/snmp_notifier/heartbeat"
 	"github.com/maxwo/snmp_notifier/httpserver"
+	"github.com/maxwo/snmp_notifier/mib"
+	"github.com/maxwo/snmp_notifier/snmpagent"
 	"github.com/maxwo/snmp_notifier/telemetry"
 	"github.com/maxwo/snmp_notifier/trapsender"
+	"github.com/maxwo/snmp_notifier/types"
+
+	"github.com/prometheus/common/version"
 )
 
 func main() {
-	configuration, logger, err := configuration.ParseConfiguration(os.Args[1:])
-	if logger == nil {
-		fmt.Fprintln(os.Stderr, "logger is nil")
-		os.Exit(1)
+	command, snmpNotifierConfiguration, logger, err := configuration.ParseCommandLine(os.Args[1:])
+	if command != nil && command.Name == configuration.CheckConfigCommand {
+		os.Exit(checkConfiguration(snmpNotifierConfiguration, err, logger))
 	}
 	if err != nil {
 		logger.Error("unable to parse configuration", "err", err.Error())
 		os.Exit(1)
 	}
 
-	logger.Debug("debugging configuration", "configuration", configuration)
+	switch command.Name {
+	case configuration.GenerateMIBCommand:
+		if err := generateMIB(*command, *snmpNotifierConfiguration); err != nil {
+			logger.Error("unable to generate the MIB module", "err", err.Error())
+			os.Exit(1)
+		}
+		return
+	case configuration.RenderCommand:
+		if err := renderTraps(*command, *snmpNotifierConfiguration, logger); err != nil {
+			logger.Error("unable to render the traps", "err", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 
-	trapSender := trapsender.New(configuration.TrapSenderConfiguration, logger)
-	alertParser := alertparser.New(configuration.AlertParserConfiguration, logger)
-	httpServer := httpserver.New(configuration.HTTPServerConfiguration, alertParser, trapSender, logger)
+	logger.Info("Starting snmp_notifier", "version", version.Info())
+	logger.Info("Build context", "build_context", version.BuildContext())
+	logger.Debug("debugging configuration", "configuration", snmpNotifierConfiguration)
+	if snmpNotifierConfiguration.TrapSenderConfiguration.DryRun {
+		logger.Warn("dry run enabled, the traps are not sent", "file", snmpNotifierConfiguration.TrapSenderConfiguration.DryRunFile)
+	}
+
+	trapSender := trapsender.New(snmpNotifierConfiguration.TrapSenderConfiguration, logger)
+	alertParser := alertparser.New(snmpNotifierConfiguration.AlertParserConfiguration, logger)
+	alertStore := alertstore.New(snmpNotifierConfiguration.AlertStoreConfiguration, trapSender, logger)
+	httpServer := httpserver.New(snmpNotifierConfiguration.HTTPServerConfiguration, alertParser, trapSender, alertStore, logger)
 
 	telemetry.Init()
+	telemetry.ConfigLastReloadSuccessful.Set(1)
+
+	alertStore.Start()
+	defer alertStore.Stop()
+
+	agent := snmpagent.New(snmpNotifierConfiguration.AgentConfiguration, alertStore, logger)
+	if snmpNotifierConfiguration.AgentConfiguration.ListenAddress != "" {
+		if err := agent.Start(); err != nil {
+			logger.Error("error while launching the SNMP agent", "err", err.Error())
+			os.Exit(1)
+		}
+		defer agent.Stop()
+	}
+
+	heartbeat := heartbeat.New(snmpNotifierConfiguration.HeartbeatConfiguration, trapSender, logger)
+	heartbeat.Start()
+	defer heartbeat.Stop()
+
+	go handleReloads(httpServer, alertStore, agent, heartbeat, logger)
 
 	if err := httpServer.Start(); err != nil {
 		logger.Error("error while launching the SNMP notifier", "err", err.Error())
 		os.Exit(1)
 	}
 }
+
+// checkConfiguration reports every problem of the configuration and its templates, and returns the exit code
+func checkConfiguration(snmpNotifierConfiguration *configuration.SNMPNotifierConfiguration, err error, logger *slog.Logger) int {
+	problems := unwrapErrors(err)
+	if snmpNotifierConfiguration != nil {
+		problems = append(problems, unwrapErrors(configuration.CheckTemplates(*snmpNotifierConfiguration, logger))...)
+	}
+
+	if len(problems) == 0 {
+		fmt.Println("configuration is valid")
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "configuration is invalid, %d problem(s) found:\n", len(problems))
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "  - %s\n", problem)
+	}
+	return 1
+}
+
+// unwrapErrors returns the errors joined into the given one
+func unwrapErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// renderTraps prints the traps generated from the alerts of a webhook notification file
+func renderTraps(command configuration.Command, snmpNotifierConfiguration configuration.SNMPNotifierConfiguration, logger *slog.Logger) error {
+	content, err := os.ReadFile(command.AlertsFile)
+	if err != nil {
+		return err
+	}
+	data := types.AlertsData{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return fmt.Errorf("invalid alerts file %s: %w", command.AlertsFile, err)
+	}
+
+	alertBucket, err := alertparser.New(snmpNotifierConfiguration.AlertParserConfiguration, logger).Parse(data)
+	if err != nil {
+		return err
+	}
+
+	// rendering neither queues traps, nor replays the spooled ones
+	trapSenderConfiguration := snmpNotifierConfiguration.TrapSenderConfiguration
+	trapSenderConfiguration.QueueSize, trapSenderConfiguration.SpoolDirectory = 0, ""
+	traps, err := trapsender.New(trapSenderConfiguration, logger).RenderTraps(*alertBucket)
+	if err != nil {
+		return err
+	}
+
+	if command.RenderFormat == configuration.JSONRenderFormat {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(traps)
+	}
+	for index, trap := range traps {
+		if index > 0 {
+			fmt.Println()
+		}
+		fmt.Print(trap)
+	}
+	return nil
+}
+
+// generateMIB writes the MIB module describing the configured user objects
+func generateMIB(command configuration.Command, snmpNotifierConfiguration configuration.SNMPNotifierConfiguration) error {
+	module, err := mib.Generate(mib.Configuration{
+		ModuleName:            command.MIBModuleName,
+		ModuleOID:             command.MIBModuleOID,
+		LastUpdated:           time.Now(),
+		TrapOID:               snmpNotifierConfiguration.AlertParserConfiguration.TrapDefaultOID,
+		ResolutionTrapOID:     snmpNotifierConfiguration.AlertParserConfiguration.TrapResolutionDefaultOID,
+		DefaultObjectsBaseOID: snmpNotifierConfiguration.AlertParserConfiguration.TrapDefaultObjectsBaseOID,
+		UserObjectsBaseOID:    snmpNotifierConfiguration.AlertParserConfiguration.TrapUserObjectsBaseOID,
+		DescriptionType:       snmpNotifierConfiguration.TrapSenderConfiguration.DescriptionType,
+		UserObjects:           snmpNotifierConfiguration.TrapSenderConfiguration.UserObjects,
+		SplitAlerts:           snmpNotifierConfiguration.TrapSenderConfiguration.MaxAlertsPerTrap > 0,
+	})
+	if err != nil {
+		return err
+	}
+
+	if command.MIBOutput == "" {
+		_, err = fmt.Print(module)
+		return err
+	}
+	return os.WriteFile(command.MIBOutput, []byte(module), 0o644)
+}
+
+// handleReloads reloads the configuration on SIGHUP or on /-/reload requests
+func handleReloads(httpServer *httpserver.HTTPServer, alertStore *alertstore.AlertStore, agent *snmpagent.SNMPAgent, heartbeat *heartbeat.Heartbeat, logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for {
+		select {
+		case <-hup:
+			if err := reloadConfiguration(httpServer, alertStore, agent, heartbeat, logger); err != nil {
+				logger.Error("error while reloading configuration", "err", err.Error())
+			}
+		case errorChannel := <-httpServer.Reload():
+			err := reloadConfiguration(httpServer, alertStore, agent, heartbeat, logger)
+			if err != nil {
+				logger.Error("error while reloading configuration", "err", err.Error())
+			}
+			errorChannel <- err
+		}
+	}
+}
+
+// reloadConfiguration parses the configuration and templates again, and keeps the current ones if they are invalid
+func reloadConfiguration(httpServer *httpserver.HTTPServer, alertStore *alertstore.AlertStore, agent *snmpagent.SNMPAgent, heartbeat *heartbeat.Heartbeat, logger *slog.Logger) error {
+	logger.Info("reloading configuration")
+
+	// the log flags are only read from the command line, which a reload does not change, so the current logger is kept
+	configuration, _, err := configuration.ParseConfiguration(os.Args[1:])
+	if err != nil {
+		telemetry.ConfigLastReloadSuccessful.Set(0)
+		return err
+	}
+
+	trapSender := trapsender.New(configuration.TrapSenderConfiguration, logger)
+	alertParser := alertparser.New(configuration.AlertParserConfiguration, logger)
+	// the agent keeps listening until a restart, and needs the alert groups
+	alertStoreConfiguration := configuration.AlertStoreConfiguration
+	alertStoreConfiguration.KeepAlerts = alertStoreConfiguration.KeepAlerts || agent.Address() != nil
+	alertStore.Update(alertStoreConfiguration, trapSender)
+	agent.Update(configuration.AgentConfiguration)
+	heartbeat.Update(configuration.HeartbeatConfiguration, trapSender)
+	httpServer.Update(alertParser, trapSender)
+
+	telemetry.ConfigLastReloadSuccessful.Set(1)
+	logger.Info("configuration reloaded")
+	return nil
+}
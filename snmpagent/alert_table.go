@@ -0,0 +1,165 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmpagent
+
+import (
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/maxwo/snmp_notifier/trapsender"
+	"github.com/maxwo/snmp_notifier/types"
+)
+
+// ActiveAlertsOID is the OID of the snmpNotifierActiveAlerts subtree of SNMP-NOTIFIER-MIB
+const ActiveAlertsOID = "1.3.6.1.4.1.98789.4"
+
+// columns of snmpNotifierActiveAlertEntry, the index column not being accessible
+const (
+	alertIDColumn            = 2
+	alertSeverityColumn      = 3
+	alertSeverityLevelColumn = 4
+	alertDescriptionColumn   = 5
+	alertStartTimeColumn     = 6
+	alertGroupKeyColumn      = 7
+	alertTrapOIDColumn       = 8
+)
+
+var (
+	activeAlertsOID = parseOID(ActiveAlertsOID)
+	// snmpNotifierActiveAlertEntry
+	alertEntryOID = slices.Concat(activeAlertsOID, []int{1, 1})
+	// snmpNotifierActiveAlertObjectEntry, whose only accessible column is the value of the object
+	alertObjectValueOID = slices.Concat(activeAlertsOID, []int{2, 1, 2})
+)
+
+// tableVariable is an instance of a column of the active alert tables
+type tableVariable struct {
+	oid []int
+	pdu gosnmp.SnmpPDU
+}
+
+// alertTable returns the instances of the active alert tables, sorted by OID. The rows are indexed by a hash of
+// the alert IDs, so that the index of an alert does not change when other alerts fire or resolve.
+func alertTable(groups map[string]types.AlertGroup, trapSender trapsender.TrapSender) ([]tableVariable, error) {
+	variables := []tableVariable{}
+	ids := slices.Sorted(maps.Keys(groups))
+	rows := alertRows(ids)
+	for _, id := range ids {
+		alertGroup := groups[id]
+		row := rows[id]
+		description, userObjects, err := trapSender.RenderObjects(alertGroup)
+		if err != nil {
+			return nil, fmt.Errorf("unable to render the objects of alert %s: %w", id, err)
+		}
+
+		addColumn := func(column int, pduType gosnmp.Asn1BER, value any) {
+			variables = append(variables, newTableVariable(slices.Concat(alertEntryOID, []int{column, row}), pduType, value))
+		}
+		addColumn(alertIDColumn, gosnmp.OctetString, id)
+		addColumn(alertSeverityColumn, gosnmp.OctetString, alertGroup.Severity)
+		addColumn(alertSeverityLevelColumn, gosnmp.Integer, alertGroup.SeverityLevel)
+		addColumn(alertDescriptionColumn, gosnmp.OctetString, description)
+		if startTime := startTime(alertGroup); !startTime.IsZero() {
+			addColumn(alertStartTimeColumn, gosnmp.OctetString, dateAndTime(startTime))
+		}
+		addColumn(alertGroupKeyColumn, gosnmp.OctetString, alertGroup.GroupKey)
+		addColumn(alertTrapOIDColumn, gosnmp.ObjectIdentifier, "."+alertGroup.TrapOID)
+
+		for subOID, value := range userObjects {
+			variables = append(variables, newTableVariable(slices.Concat(alertObjectValueOID, []int{row, subOID}), gosnmp.OctetString, value))
+		}
+	}
+
+	slices.SortFunc(variables, func(first tableVariable, second tableVariable) int {
+		return slices.Compare(first.oid, second.oid)
+	})
+	return variables, nil
+}
+
+// alertRows returns the row index of each alert ID, the 31 lowest bits of its FNV-1a hash. On a collision, the
+// greatest ID takes the next free index.
+func alertRows(ids []string) map[string]int {
+	rows := make(map[string]int, len(ids))
+	used := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		hash := fnv.New32a()
+		hash.Write([]byte(id))
+		row := int(hash.Sum32() & math.MaxInt32)
+		for row == 0 || used[row] {
+			row = (row + 1) & math.MaxInt32
+		}
+		used[row] = true
+		rows[id] = row
+	}
+	return rows
+}
+
+func newTableVariable(oid []int, pduType gosnmp.Asn1BER, value any) tableVariable {
+	return tableVariable{
+		oid: oid,
+		pdu: gosnmp.SnmpPDU{Name: formatOID(oid), Type: pduType, Value: value},
+	}
+}
+
+// startTime returns the time the first firing alert of a group started at
+func startTime(alertGroup types.AlertGroup) time.Time {
+	var first time.Time
+	for _, alert := range alertGroup.Alerts {
+		if !alert.StartsAt.IsZero() && (first.IsZero() || alert.StartsAt.Before(first)) {
+			first = alert.StartsAt
+		}
+	}
+	return first
+}
+
+// dateAndTime encodes a time as a DateAndTime of SNMPv2-TC, in UTC
+func dateAndTime(value time.Time) []byte {
+	value = value.UTC()
+	return []byte{
+		byte(value.Year() >> 8), byte(value.Year()), byte(value.Month()), byte(value.Day()),
+		byte(value.Hour()), byte(value.Minute()), byte(value.Second()), byte(value.Nanosecond() / int(100*time.Millisecond)),
+		'+', 0, 0,
+	}
+}
+
+// parseOID parses a numeric OID, with or without a leading dot, returning nil when it is invalid
+func parseOID(oid string) []int {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	subIDs := make([]int, 0, len(parts))
+	for _, part := range parts {
+		subID, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil
+		}
+		subIDs = append(subIDs, int(subID))
+	}
+	return subIDs
+}
+
+func formatOID(oid []int) string {
+	builder := strings.Builder{}
+	for _, subID := range oid {
+		builder.WriteString(".")
+		builder.WriteString(strconv.Itoa(subID))
+	}
+	return builder.String()
+}
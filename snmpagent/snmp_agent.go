@@ -0,0 +1,328 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmpagent
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/maxwo/snmp_notifier/alertstore"
+	"github.com/maxwo/snmp_notifier/telemetry"
+)
+
+const (
+	// maxMessageSize is the largest SNMP message sent back, the largest UDP payload
+	maxMessageSize = 65507
+	// maxRepetitions limits the variables returned by a GETBULK request
+	maxRepetitions = 1000
+)
+
+// tableTTL is the longest time the active alert tables are cached, as their templates may depend on the time
+var tableTTL = 10 * time.Second
+
+// SNMPAgent answers the GET, GETNEXT and GETBULK requests on the active alert tables, with the firing alert groups
+// of the alert store. Only SNMP v1 and v2c are supported.
+type SNMPAgent struct {
+	configuration Configuration
+	alertStore    *alertstore.AlertStore
+	logger        *slog.Logger
+	connection    net.PacketConn
+	mutex         sync.RWMutex
+	// table caches the active alert tables, rendered from a version of the alert store
+	table      *tableSnapshot
+	tableMutex sync.Mutex
+}
+
+type tableSnapshot struct {
+	version    uint64
+	renderedAt time.Time
+	variables  []tableVariable
+}
+
+// Configuration describes the SNMP agent
+type Configuration struct {
+	// ListenAddress is the UDP address the agent listens on, the agent being disabled when empty
+	ListenAddress string
+	// Community is the community the requests must use
+	Community string
+}
+
+// New creates an SNMPAgent
+func New(configuration Configuration, alertStore *alertstore.AlertStore, logger *slog.Logger) *SNMPAgent {
+	return &SNMPAgent{
+		configuration: configuration,
+		alertStore:    alertStore,
+		logger:        logger,
+	}
+}
+
+// Update replaces the community of the agent. A new listen address requires a restart.
+func (agent *SNMPAgent) Update(configuration Configuration) {
+	agent.mutex.Lock()
+	defer agent.mutex.Unlock()
+	if configuration.ListenAddress != agent.configuration.ListenAddress {
+		agent.logger.Warn("the SNMP agent listen address is only changed on restart", "address", agent.configuration.ListenAddress)
+	}
+	agent.configuration.Community = configuration.Community
+}
+
+// Start listens for SNMP requests, and answers them in the background until the agent is stopped
+func (agent *SNMPAgent) Start() error {
+	connection, err := net.ListenPacket("udp", agent.configuration.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("unable to listen for SNMP requests on %s: %w", agent.configuration.ListenAddress, err)
+	}
+	agent.connection = connection
+	agent.logger.Info("SNMP agent listening", "address", connection.LocalAddr().String())
+
+	go agent.serve()
+	return nil
+}
+
+// Stop stops listening for SNMP requests
+func (agent *SNMPAgent) Stop() error {
+	if agent.connection == nil {
+		return nil
+	}
+	return agent.connection.Close()
+}
+
+// Address returns the address the agent listens on, once started
+func (agent *SNMPAgent) Address() net.Addr {
+	if agent.connection == nil {
+		return nil
+	}
+	return agent.connection.LocalAddr()
+}
+
+func (agent *SNMPAgent) serve() {
+	buffer := make([]byte, 65535)
+	for {
+		length, address, err := agent.connection.ReadFrom(buffer)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			agent.logger.Error("error while reading an SNMP request", "err", err.Error())
+			continue
+		}
+
+		response, err := agent.handle(buffer[:length])
+		if err != nil {
+			agent.logger.Debug("SNMP request ignored", "address", address.String(), "err", err.Error())
+			continue
+		}
+		if _, err := agent.connection.WriteTo(response, address); err != nil {
+			agent.logger.Error("error while sending an SNMP response", "address", address.String(), "err", err.Error())
+		}
+	}
+}
+
+// handle decodes a request and returns the encoded response, or an error when the request is ignored
+func (agent *SNMPAgent) handle(message []byte) (response []byte, err error) {
+	defer func() {
+		// gosnmp may panic on malformed messages
+		if recovered := recover(); recovered != nil {
+			response, err = nil, fmt.Errorf("invalid SNMP request: %v", recovered)
+			telemetry.SNMPAgentRequestsTotal.WithLabelValues("invalid").Inc()
+		}
+	}()
+
+	request, err := (&gosnmp.GoSNMP{}).SnmpDecodePacket(message)
+	if err != nil || request.Version == gosnmp.Version3 {
+		telemetry.SNMPAgentRequestsTotal.WithLabelValues("invalid").Inc()
+		if err == nil {
+			err = errors.New("SNMP v3 requests are not supported")
+		}
+		return nil, err
+	}
+
+	agent.mutex.RLock()
+	community := agent.configuration.Community
+	agent.mutex.RUnlock()
+	if request.Community != community {
+		telemetry.SNMPAgentRequestsTotal.WithLabelValues("unknown_community").Inc()
+		return nil, errors.New("unknown community")
+	}
+
+	packet, err := agent.respond(request)
+	if err != nil {
+		telemetry.SNMPAgentRequestsTotal.WithLabelValues("invalid").Inc()
+		return nil, err
+	}
+	telemetry.SNMPAgentRequestsTotal.WithLabelValues("success").Inc()
+	return packet, nil
+}
+
+// respond computes the response to a decoded request, and encodes it
+func (agent *SNMPAgent) respond(request *gosnmp.SnmpPacket) ([]byte, error) {
+	response := &gosnmp.SnmpPacket{
+		Version:   request.Version,
+		Community: request.Community,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: request.RequestID,
+	}
+
+	table, err := agent.activeAlertTable()
+	if err != nil {
+		agent.logger.Error("error while generating the active alert table", "err", err.Error())
+		return errorResponse(response, request.Variables, gosnmp.GenErr, 0)
+	}
+
+	switch request.PDUType {
+	case gosnmp.GetRequest:
+		response.Variables = make([]gosnmp.SnmpPDU, 0, len(request.Variables))
+		for _, variable := range request.Variables {
+			response.Variables = append(response.Variables, get(table, variable.Name))
+		}
+	case gosnmp.GetNextRequest:
+		response.Variables = make([]gosnmp.SnmpPDU, 0, len(request.Variables))
+		for _, variable := range request.Variables {
+			response.Variables = append(response.Variables, getNext(table, variable.Name))
+		}
+	case gosnmp.GetBulkRequest:
+		if request.Version == gosnmp.Version1 {
+			return nil, errors.New("GETBULK requests are not supported with SNMP v1")
+		}
+		response.Variables = getBulk(table, request.Variables, int(request.NonRepeaters), int(min(request.MaxRepetitions, maxRepetitions)))
+	case gosnmp.SetRequest:
+		// the tables are read-only
+		if request.Version == gosnmp.Version1 {
+			return errorResponse(response, request.Variables, gosnmp.NoSuchName, 1)
+		}
+		return errorResponse(response, request.Variables, gosnmp.NotWritable, 1)
+	default:
+		return nil, fmt.Errorf("unsupported PDU type %s", request.PDUType)
+	}
+
+	if request.Version == gosnmp.Version1 {
+		// SNMP v1 has no exception values, a missing variable fails the request
+		for index, variable := range response.Variables {
+			if isException(variable) {
+				return errorResponse(response, request.Variables, gosnmp.NoSuchName, index+1)
+			}
+		}
+	}
+
+	packet, err := response.MarshalMsg()
+	if err != nil {
+		return nil, err
+	}
+	if request.PDUType == gosnmp.GetBulkRequest {
+		// the repetitions are cut until the response fits
+		for len(packet) > maxMessageSize && len(response.Variables) > int(request.NonRepeaters) {
+			response.Variables = response.Variables[:max(len(response.Variables)/2, int(request.NonRepeaters))]
+			if packet, err = response.MarshalMsg(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(packet) > maxMessageSize {
+		return errorResponse(response, request.Variables, gosnmp.TooBig, 0)
+	}
+	return packet, nil
+}
+
+// activeAlertTable returns the active alert tables, rendered again only when the alert store changed or the tables
+// expired, so that walking the tables does not render the templates at every request
+func (agent *SNMPAgent) activeAlertTable() ([]tableVariable, error) {
+	agent.tableMutex.Lock()
+	defer agent.tableMutex.Unlock()
+	if agent.table != nil && agent.table.version == agent.alertStore.Version() && time.Since(agent.table.renderedAt) < tableTTL {
+		return agent.table.variables, nil
+	}
+
+	groups, trapSender, version := agent.alertStore.Groups()
+	variables, err := alertTable(groups, trapSender)
+	if err != nil {
+		return nil, err
+	}
+	agent.table = &tableSnapshot{version: version, renderedAt: time.Now(), variables: variables}
+	return variables, nil
+}
+
+// errorResponse encodes an error response, with the variables of the request
+func errorResponse(response *gosnmp.SnmpPacket, variables []gosnmp.SnmpPDU, status gosnmp.SNMPError, index int) ([]byte, error) {
+	response.Error = status
+	response.ErrorIndex = uint8(min(index, 255))
+	response.Variables = make([]gosnmp.SnmpPDU, 0, len(variables))
+	for _, variable := range variables {
+		response.Variables = append(response.Variables, gosnmp.SnmpPDU{Name: variable.Name, Type: gosnmp.Null})
+	}
+	return response.MarshalMsg()
+}
+
+// get returns the variable of an OID, or the reason why it does not exist
+func get(table []tableVariable, name string) gosnmp.SnmpPDU {
+	oid := parseOID(name)
+	index, found := sort.Find(len(table), func(index int) int {
+		return slices.Compare(oid, table[index].oid)
+	})
+	if found {
+		return table[index].pdu
+	}
+	if oid != nil && len(oid) > len(activeAlertsOID) && slices.Equal(oid[:len(activeAlertsOID)], activeAlertsOID) {
+		return gosnmp.SnmpPDU{Name: name, Type: gosnmp.NoSuchInstance}
+	}
+	return gosnmp.SnmpPDU{Name: name, Type: gosnmp.NoSuchObject}
+}
+
+// getNext returns the variable following an OID, or the end of the MIB view
+func getNext(table []tableVariable, name string) gosnmp.SnmpPDU {
+	oid := parseOID(name)
+	index := sort.Search(len(table), func(index int) bool {
+		return slices.Compare(table[index].oid, oid) > 0
+	})
+	if index < len(table) {
+		return table[index].pdu
+	}
+	return gosnmp.SnmpPDU{Name: name, Type: gosnmp.EndOfMibView}
+}
+
+// getBulk returns the variables following the non-repeaters once, then those following the other variables,
+// repeatedly, as described by RFC 3416
+func getBulk(table []tableVariable, variables []gosnmp.SnmpPDU, nonRepeaters int, repetitions int) []gosnmp.SnmpPDU {
+	nonRepeaters = min(nonRepeaters, len(variables))
+	results := []gosnmp.SnmpPDU{}
+	for _, variable := range variables[:nonRepeaters] {
+		results = append(results, getNext(table, variable.Name))
+	}
+
+	repeaters := slices.Clone(variables[nonRepeaters:])
+	for repetition := 0; repetition < repetitions && len(repeaters) > 0; repetition++ {
+		ended := true
+		for index, variable := range repeaters {
+			next := getNext(table, variable.Name)
+			results = append(results, next)
+			repeaters[index] = next
+			ended = ended && next.Type == gosnmp.EndOfMibView
+		}
+		if ended {
+			break
+		}
+	}
+	return results
+}
+
+func isException(variable gosnmp.SnmpPDU) bool {
+	return variable.Type == gosnmp.NoSuchObject || variable.Type == gosnmp.NoSuchInstance || variable.Type == gosnmp.EndOfMibView
+}
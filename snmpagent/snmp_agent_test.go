@@ -0,0 +1,297 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmpagent
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/maxwo/snmp_notifier/alertstore"
+	"github.com/maxwo/snmp_notifier/trapsender"
+	"github.com/maxwo/snmp_notifier/types"
+)
+
+const (
+	alertEntry       = ".1.3.6.1.4.1.98789.4.1.1"
+	alertObjectEntry = ".1.3.6.1.4.1.98789.4.2.1"
+	// the row of the firing alert of test_mixed_bucket.json, a hash of its ID
+	alertRow = ".1535234980"
+)
+
+var startsAt = time.Date(2026, time.October, 17, 8, 30, 15, 500000000, time.UTC)
+
+func TestGetActiveAlerts(t *testing.T) {
+	agent := startAgent(t)
+	client := newClient(t, agent, gosnmp.Version2c, "alerts")
+
+	result, err := client.Get([]string{
+		alertEntry + ".2" + alertRow,
+		alertEntry + ".3" + alertRow,
+		alertEntry + ".4" + alertRow,
+		alertEntry + ".5" + alertRow,
+		alertEntry + ".6" + alertRow,
+		alertEntry + ".8" + alertRow,
+		alertObjectEntry + ".2" + alertRow + ".4",
+		alertEntry + ".2.2",
+		".1.3.6.1.2.1.1.1.0",
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expected := []any{
+		"1.2.3.2.1[environment=production,label=test]",
+		"critical",
+		1,
+		"2 alerts are firing",
+		string(dateAndTime(startsAt)),
+		".1.2.3.2.1",
+		"critical alerts: 2",
+	}
+	for index, value := range expected {
+		variable := result.Variables[index]
+		if actual := variableValue(variable); actual != value {
+			t.Errorf("unexpected value of %s: expected %v, got %v", variable.Name, value, actual)
+		}
+	}
+	if result.Variables[7].Type != gosnmp.NoSuchInstance {
+		t.Error("a missing row should not exist", result.Variables[7])
+	}
+	if result.Variables[8].Type != gosnmp.NoSuchObject {
+		t.Error("an OID outside the active alerts should not exist", result.Variables[8])
+	}
+}
+
+func TestWalkActiveAlerts(t *testing.T) {
+	agent := startAgent(t)
+
+	for _, version := range []gosnmp.SnmpVersion{gosnmp.Version1, gosnmp.Version2c} {
+		client := newClient(t, agent, version, "alerts")
+		walk := client.Walk
+		if version == gosnmp.Version2c {
+			walk = client.BulkWalk
+		}
+
+		names := []string{}
+		err := walk("."+ActiveAlertsOID, func(variable gosnmp.SnmpPDU) error {
+			names = append(names, variable.Name)
+			return nil
+		})
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+
+		// the 7 columns of the only firing alert, and its user object
+		expected := []string{
+			alertEntry + ".2" + alertRow, alertEntry + ".3" + alertRow, alertEntry + ".4" + alertRow, alertEntry + ".5" + alertRow,
+			alertEntry + ".6" + alertRow, alertEntry + ".7" + alertRow, alertEntry + ".8" + alertRow, alertObjectEntry + ".2" + alertRow + ".4",
+		}
+		if len(names) != len(expected) {
+			t.Fatalf("unexpected variables with version %s: expected %v, got %v", version, expected, names)
+		}
+		for index, name := range expected {
+			if names[index] != name {
+				t.Errorf("unexpected variable with version %s: expected %s, got %s", version, name, names[index])
+			}
+		}
+	}
+}
+
+func TestV1MissingVariable(t *testing.T) {
+	agent := startAgent(t)
+	client := newClient(t, agent, gosnmp.Version1, "alerts")
+
+	result, err := client.Get([]string{alertEntry + ".2" + alertRow, alertEntry + ".2.2"})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if result.Error != gosnmp.NoSuchName || result.ErrorIndex != 2 {
+		t.Error("a missing variable should fail the request with SNMP v1", result.Error, result.ErrorIndex)
+	}
+}
+
+func TestReadOnlyActiveAlerts(t *testing.T) {
+	agent := startAgent(t)
+	client := newClient(t, agent, gosnmp.Version2c, "alerts")
+
+	result, err := client.Set([]gosnmp.SnmpPDU{{Name: alertEntry + ".3" + alertRow, Type: gosnmp.OctetString, Value: "info"}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if result.Error != gosnmp.NotWritable {
+		t.Error("the active alerts should not be writable", result.Error)
+	}
+}
+
+func TestUnknownCommunity(t *testing.T) {
+	agent := startAgent(t)
+	client := newClient(t, agent, gosnmp.Version2c, "public")
+	client.Retries = 0
+	client.Timeout = 200 * time.Millisecond
+
+	if _, err := client.Get([]string{alertEntry + ".2" + alertRow}); err == nil {
+		t.Error("the requests with an unknown community should be ignored")
+	}
+
+	agent.Update(Configuration{ListenAddress: agent.configuration.ListenAddress, Community: "public"})
+	if _, err := client.Get([]string{alertEntry + ".2" + alertRow}); err != nil {
+		t.Error("the community should be updated", err)
+	}
+}
+
+func TestInvalidRequest(t *testing.T) {
+	agent := New(Configuration{Community: "alerts"}, nil, newLogger())
+	if _, err := agent.handle([]byte{0x30, 0x03, 0x02, 0x01}); err == nil {
+		t.Error("an invalid request should be ignored")
+	}
+}
+
+func TestCachedActiveAlerts(t *testing.T) {
+	renders := atomic.Int32{}
+	trapSender := trapsender.New(trapsender.Configuration{
+		DescriptionTemplate: *template.Must(template.New("description").Funcs(template.FuncMap{
+			"rendered": func() string { renders.Add(1); return "" },
+		}).Parse(`{{ rendered }}{{ len .Alerts }} alerts are firing`)),
+	}, newLogger())
+	alertStore := alertstore.New(alertstore.Configuration{KeepAlerts: true}, trapSender, newLogger())
+	alertStore.Record(readBucketFile(t))
+
+	agent := New(Configuration{ListenAddress: "127.0.0.1:0", Community: "alerts"}, alertStore, newLogger())
+	if err := agent.Start(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	defer agent.Stop()
+	client := newClient(t, agent, gosnmp.Version2c, "alerts")
+	client.MaxRepetitions = 1
+
+	walk := func() {
+		if _, err := client.BulkWalkAll("." + ActiveAlertsOID); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+
+	// the only firing alert is rendered once, whatever the requests
+	walk()
+	walk()
+	if count := renders.Load(); count != 1 {
+		t.Errorf("the active alerts should be rendered once, got %d renders", count)
+	}
+
+	alertStore.Record(readBucketFile(t))
+	walk()
+	if count := renders.Load(); count != 2 {
+		t.Errorf("the active alerts should be rendered again once changed, got %d renders", count)
+	}
+
+	// the TTL is read under the lock of the tables, by the goroutine serving the requests
+	agent.tableMutex.Lock()
+	defer func(ttl time.Duration) { tableTTL = ttl }(tableTTL)
+	tableTTL = 0
+	agent.tableMutex.Unlock()
+	if _, err := agent.activeAlertTable(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if count := renders.Load(); count != 3 {
+		t.Errorf("the active alerts should be rendered again once expired, got %d renders", count)
+	}
+}
+
+func TestStableAlertRows(t *testing.T) {
+	id := "1.2.3.2.1[environment=production,label=test]"
+	row := alertRows([]string{id})[id]
+	if formatOID([]int{row}) != alertRow {
+		t.Errorf("unexpected row of %s: expected %s, got %d", id, alertRow, row)
+	}
+
+	// the row of an alert does not depend on the other alerts
+	rows := alertRows([]string{"1.2.3.1.1[]", id, "1.2.3.3.1[]"})
+	if rows[id] != row || len(rows) != 3 {
+		t.Errorf("the row of %s should not change when other alerts fire, got %v", id, rows)
+	}
+}
+
+func startAgent(t *testing.T) *SNMPAgent {
+	trapSender := trapsender.New(trapsender.Configuration{
+		DescriptionTemplate: *template.Must(template.New("description").Parse(`{{ len .Alerts }} alerts are firing`)),
+		UserObjects: []trapsender.UserObject{
+			{SubOID: 4, Type: trapsender.StringObjectType, ContentTemplate: *template.Must(template.New("object").Parse(`{{ .Severity }} alerts: {{ len .Alerts }}`))},
+		},
+	}, newLogger())
+
+	alertStore := alertstore.New(alertstore.Configuration{KeepAlerts: true}, trapSender, newLogger())
+	alertStore.Record(readBucketFile(t))
+
+	agent := New(Configuration{ListenAddress: "127.0.0.1:0", Community: "alerts"}, alertStore, newLogger())
+	if err := agent.Start(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	t.Cleanup(func() { agent.Stop() })
+	return agent
+}
+
+func newClient(t *testing.T, agent *SNMPAgent, version gosnmp.SnmpVersion, community string) *gosnmp.GoSNMP {
+	host, port, _ := net.SplitHostPort(agent.Address().String())
+	portNumber, _ := strconv.Atoi(port)
+	client := &gosnmp.GoSNMP{
+		Target:         host,
+		Port:           uint16(portNumber),
+		Version:        version,
+		Community:      community,
+		Timeout:        2 * time.Second,
+		Retries:        1,
+		MaxRepetitions: 3,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	t.Cleanup(func() { client.Conn.Close() })
+	return client
+}
+
+func newLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, nil))
+}
+
+func readBucketFile(t *testing.T) types.AlertBucket {
+	content, err := os.ReadFile("../trapsender/test_mixed_bucket.json")
+	if err != nil {
+		t.Fatal("Error while reading bucket file:", err)
+	}
+	bucket := types.AlertBucket{}
+	if err := json.Unmarshal(content, &bucket); err != nil {
+		t.Fatal("Error while parsing bucket file:", err)
+	}
+	for _, alertGroup := range bucket.AlertGroups {
+		for index := range alertGroup.Alerts {
+			alertGroup.Alerts[index].StartsAt = startsAt.Add(time.Duration(index) * time.Minute)
+		}
+	}
+	return bucket
+}
+
+func variableValue(variable gosnmp.SnmpPDU) any {
+	if value, ok := variable.Value.([]byte); ok {
+		return string(value)
+	}
+	return variable.Value
+}
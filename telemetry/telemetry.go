@@ -13,7 +13,10 @@
 
 package telemetry
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
 
 var (
 	// RequestTotal counts the number of received HTTP calls
@@ -32,10 +35,108 @@ var (
 		},
 		[]string{"destination", "outcome"},
 	)
+	// SNMPQueueLength counts the traps waiting to be sent asynchronously
+	SNMPQueueLength = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "snmp_notifier_queue_length",
+			Help: "Number of traps waiting to be sent by SNMP destination.",
+		},
+		[]string{"destination"},
+	)
+	// SNMPQueueWaitSeconds measures the time spent by traps in the queue before being sent
+	SNMPQueueWaitSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "snmp_notifier_queue_wait_seconds",
+			Help:    "Time spent by traps in the queue before being sent by SNMP destination.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		},
+		[]string{"destination"},
+	)
+	// SNMPSpoolLength counts the traps persisted in the spool, waiting to be replayed
+	SNMPSpoolLength = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "snmp_notifier_spool_length",
+			Help: "Number of spooled traps waiting to be replayed by SNMP destination.",
+		},
+		[]string{"destination"},
+	)
+	// SNMPSpoolDroppedTotal counts the spooled traps dropped without being replayed
+	SNMPSpoolDroppedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "snmp_notifier_spool_dropped_traps_total",
+			Help: "Total number of spooled traps dropped without being replayed by SNMP destination and reason.",
+		},
+		[]string{"destination", "reason"},
+	)
+	// SNMPTruncatedTrapsTotal counts the traps whose objects were truncated to fit the maximum sizes
+	SNMPTruncatedTrapsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "snmp_notifier_truncated_traps_total",
+			Help: "Total number of traps whose objects were truncated to fit the maximum varbind and PDU sizes.",
+		},
+	)
+	// ActiveAlertGroups counts the firing alert groups kept to be re-notified or exposed by the SNMP agent
+	ActiveAlertGroups = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "snmp_notifier_active_alert_groups",
+			Help: "Number of firing alert groups kept to be re-notified or exposed by the SNMP agent.",
+		},
+	)
+	// SNMPAgentRequestsTotal counts the SNMP requests received by the agent
+	SNMPAgentRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "snmp_notifier_agent_requests_total",
+			Help: "Total number of SNMP requests received by the agent by outcome.",
+		},
+		[]string{"outcome"},
+	)
+	// ConfigLastReloadSuccessful tells whether the last configuration reload succeeded
+	ConfigLastReloadSuccessful = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "snmp_notifier_config_last_reload_successful",
+			Help: "Whether the last configuration reload attempt was successful.",
+		},
+	)
 )
 
 // Init starts Prometheus metric counters collection
 func Init() {
 	prometheus.Register(RequestTotal)
 	prometheus.Register(SNMPTrapTotal)
+	prometheus.Register(SNMPQueueLength)
+	prometheus.Register(SNMPQueueWaitSeconds)
+	prometheus.Register(SNMPSpoolLength)
+	prometheus.Register(SNMPSpoolDroppedTotal)
+	prometheus.Register(SNMPTruncatedTrapsTotal)
+	prometheus.Register(ActiveAlertGroups)
+	prometheus.Register(SNMPAgentRequestsTotal)
+	prometheus.Register(ConfigLastReloadSuccessful)
+}
+
+// TrapCount returns the number of traps counted with the given outcomes since the start, for all destinations
+func TrapCount(outcomes ...string) uint64 {
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		SNMPTrapTotal.Collect(metrics)
+		close(metrics)
+	}()
+
+	count := 0.0
+	for metric := range metrics {
+		written := &dto.Metric{}
+		if err := metric.Write(written); err != nil {
+			continue
+		}
+		for _, label := range written.GetLabel() {
+			if label.GetName() != "outcome" {
+				continue
+			}
+			for _, outcome := range outcomes {
+				if label.GetValue() == outcome {
+					count += written.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return uint64(count)
 }
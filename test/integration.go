@@ -61,7 +61,8 @@ func LaunchTrapReceiver() (*int32, *snmpgo.TrapServer, chan *snmpgo.TrapRequest,
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	traps := make(chan *snmpgo.TrapRequest)
+	// buffered, so that informs are acknowledged before the traps are read
+	traps := make(chan *snmpgo.TrapRequest, 100)
 	go launchSNMPServer(trapServer, traps)
 	time.Sleep(200 * time.Millisecond)
 	return &port, trapServer, traps, nil
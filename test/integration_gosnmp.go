@@ -0,0 +1,96 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/k-sone/snmpgo"
+)
+
+// LaunchGoSNMPTrapReceiver provides a SNMPv3 server for testing purposes, supporting the SHA-2 authentication
+// and the AES-192/256 privacy protocols that are not available with snmpgo.
+// The user, passwords and engine ID are the same as the ones of LaunchTrapReceiver.
+func LaunchGoSNMPTrapReceiver(authenticationProtocol gosnmp.SnmpV3AuthProtocol, privateProtocol gosnmp.SnmpV3PrivProtocol) (*int32, *gosnmp.TrapListener, chan *snmpgo.TrapRequest, error) {
+	port := 10000 + rand.Int32()%10000
+
+	engineID, _ := hex.DecodeString("8000000004736e6d70676f")
+
+	traps := make(chan *snmpgo.TrapRequest, 100)
+	trapListener := gosnmp.NewTrapListener()
+	trapListener.Params = &gosnmp.GoSNMP{
+		Version:       gosnmp.Version3,
+		Timeout:       5 * time.Second,
+		SecurityModel: gosnmp.UserSecurityModel,
+		MsgFlags:      gosnmp.AuthPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			UserName:                 "v3_username",
+			AuthoritativeEngineID:    string(engineID),
+			AuthenticationProtocol:   authenticationProtocol,
+			AuthenticationPassphrase: "v3_password",
+			PrivacyProtocol:          privateProtocol,
+			PrivacyPassphrase:        "v3_private_secret",
+		},
+	}
+	trapListener.OnNewTrap = func(packet *gosnmp.SnmpPacket, source *net.UDPAddr) {
+		log.Print("trap received in listener: ", packet.PDUType, " ", packet.Variables, " ", source)
+		traps <- &snmpgo.TrapRequest{
+			Pdu:    snmpgo.NewPduWithVarBinds(snmpgo.V3, snmpgo.SNMPTrapV2, toVarBinds(packet.Variables)),
+			Source: source,
+		}
+	}
+
+	errors := make(chan error, 1)
+	go func() {
+		errors <- trapListener.Listen(fmt.Sprintf("127.0.0.1:%d", port))
+	}()
+
+	select {
+	case <-trapListener.Listening():
+		return &port, trapListener, traps, nil
+	case err := <-errors:
+		return nil, nil, nil, err
+	}
+}
+
+func toVarBinds(variables []gosnmp.SnmpPDU) snmpgo.VarBinds {
+	varBinds := snmpgo.VarBinds{}
+	for _, variable := range variables {
+		oid, err := snmpgo.NewOid(strings.TrimPrefix(variable.Name, "."))
+		if err != nil {
+			continue
+		}
+		switch variable.Type {
+		case gosnmp.OctetString:
+			varBinds = append(varBinds, snmpgo.NewVarBind(oid, snmpgo.NewOctetString(variable.Value.([]byte))))
+		case gosnmp.ObjectIdentifier:
+			value, err := snmpgo.NewOid(strings.TrimPrefix(variable.Value.(string), "."))
+			if err == nil {
+				varBinds = append(varBinds, snmpgo.NewVarBind(oid, value))
+			}
+		case gosnmp.TimeTicks:
+			varBinds = append(varBinds, snmpgo.NewVarBind(oid, snmpgo.NewTimeTicks(variable.Value.(uint32))))
+		default:
+			varBinds = append(varBinds, snmpgo.NewVarBind(oid, snmpgo.NewOctetString([]byte(fmt.Sprint(variable.Value)))))
+		}
+	}
+	return varBinds
+}
@@ -0,0 +1,120 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"log"
+	"math/big"
+	"math/rand/v2"
+	"net"
+
+	"github.com/k-sone/snmpgo"
+)
+
+var (
+	oidSnmpTraps          = snmpgo.MustNewOid("1.3.6.1.6.3.1.1.5")
+	oidSnmpTrapEnterprise = snmpgo.MustNewOid("1.3.6.1.6.3.1.1.4.3.0")
+	oidSnmpTrapAddress    = snmpgo.MustNewOid("1.3.6.1.6.3.18.1.3.0")
+	oidSnmpTrapCommunity  = snmpgo.MustNewOid("1.3.6.1.6.3.18.1.4.0")
+)
+
+// LaunchV1TrapReceiver provides a SNMPv1 server for testing purposes.
+// The received SNMPv1 traps are translated into SNMPv2 traps according to RFC 3584 section 3.1,
+// so that their enterprise, agent address and community are available as variables.
+func LaunchV1TrapReceiver() (*int32, net.PacketConn, chan *snmpgo.TrapRequest, error) {
+	port := 10000 + rand.Int32()%10000
+	connection, err := net.ListenPacket("udp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	traps := make(chan *snmpgo.TrapRequest, 100)
+	go func() {
+		buffer := make([]byte, 65535)
+		for {
+			length, source, err := connection.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			pdu, err := unmarshalV1Trap(buffer[:length])
+			log.Print("v1 trap received: ", pdu, " ", source, " ", err)
+			traps <- &snmpgo.TrapRequest{Pdu: pdu, Source: source, Error: err}
+		}
+	}()
+
+	return &port, connection, traps, nil
+}
+
+func unmarshalV1Trap(packet []byte) (snmpgo.Pdu, error) {
+	var message struct {
+		Version   int
+		Community []byte
+		Pdu       asn1.RawValue
+	}
+	if _, err := asn1.Unmarshal(packet, &message); err != nil {
+		return nil, err
+	}
+	if message.Version != 0 || message.Pdu.Class != asn1.ClassContextSpecific || message.Pdu.Tag != 4 {
+		return nil, fmt.Errorf("not an SNMPv1 trap")
+	}
+
+	var (
+		enterprise   asn1.ObjectIdentifier
+		agentAddress asn1.RawValue
+		genericTrap  int
+		specificTrap int
+		timeStamp    asn1.RawValue
+		varBinds     asn1.RawValue
+	)
+	rest := message.Pdu.Bytes
+	for _, field := range []interface{}{&enterprise, &agentAddress, &genericTrap, &specificTrap, &timeStamp, &varBinds} {
+		var err error
+		if rest, err = asn1.Unmarshal(rest, field); err != nil {
+			return nil, err
+		}
+	}
+	if len(agentAddress.Bytes) != 4 {
+		return nil, fmt.Errorf("invalid agent address")
+	}
+
+	trapOID := &snmpgo.Oid{Value: append(append(asn1.ObjectIdentifier{}, enterprise...), 0, specificTrap)}
+	if genericTrap != 6 {
+		trapOID = &snmpgo.Oid{Value: append(append(asn1.ObjectIdentifier{}, oidSnmpTraps.Value...), genericTrap+1)}
+	}
+
+	result := snmpgo.VarBinds{
+		snmpgo.NewVarBind(snmpgo.OidSysUpTime, snmpgo.NewTimeTicks(uint32(new(big.Int).SetBytes(timeStamp.Bytes).Uint64()))),
+		snmpgo.NewVarBind(snmpgo.OidSnmpTrap, trapOID),
+	}
+	rest = varBinds.Bytes
+	for len(rest) > 0 {
+		var (
+			varBind snmpgo.VarBind
+			err     error
+		)
+		if rest, err = varBind.Unmarshal(rest); err != nil {
+			return nil, err
+		}
+		result = append(result, &varBind)
+	}
+	result = append(result,
+		snmpgo.NewVarBind(oidSnmpTrapAddress, snmpgo.NewIpaddress(agentAddress.Bytes[0], agentAddress.Bytes[1], agentAddress.Bytes[2], agentAddress.Bytes[3])),
+		snmpgo.NewVarBind(oidSnmpTrapCommunity, snmpgo.NewOctetString(message.Community)),
+		snmpgo.NewVarBind(oidSnmpTrapEnterprise, &snmpgo.Oid{Value: enterprise}),
+	)
+
+	return snmpgo.NewPduWithVarBinds(snmpgo.V1, snmpgo.Trap, result), nil
+}
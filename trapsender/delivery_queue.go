@@ -0,0 +1,135 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trapsender
+
+import (
+	"sync"
+	"time"
+
+	"github.com/k-sone/snmpgo"
+
+	"github.com/maxwo/snmp_notifier/telemetry"
+)
+
+// deliveryQueue holds the traps waiting to be sent to a destination by a pool of workers
+type deliveryQueue struct {
+	traps chan queuedTrap
+	// done is closed with the queue, to interrupt the retry delays
+	done   chan struct{}
+	mutex  sync.RWMutex
+	closed bool
+}
+
+type queuedTrap struct {
+	varBinds   snmpgo.VarBinds
+	enqueuedAt time.Time
+	// spool tells whether the trap is spooled once its retries failed
+	spool bool
+}
+
+// startDeliveryQueues starts a queue and its workers for every destination
+func (trapSender TrapSender) startDeliveryQueues() []*deliveryQueue {
+	queues := make([]*deliveryQueue, len(trapSender.configuration.SNMPDestinations))
+	for index := range queues {
+		queues[index] = &deliveryQueue{
+			traps: make(chan queuedTrap, trapSender.configuration.QueueSize),
+			done:  make(chan struct{}),
+		}
+		for range trapSender.configuration.QueueWorkers {
+			go trapSender.deliver(index, queues[index])
+		}
+	}
+	return queues
+}
+
+// enqueue adds a trap to the queue, unless it is full or closed
+func (queue *deliveryQueue) enqueue(varBinds snmpgo.VarBinds, spool bool) bool {
+	queue.mutex.RLock()
+	defer queue.mutex.RUnlock()
+	if queue.closed {
+		return false
+	}
+	select {
+	case queue.traps <- queuedTrap{varBinds: varBinds, enqueuedAt: time.Now(), spool: spool}:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops accepting traps, the workers stop once the queued traps are sent
+func (queue *deliveryQueue) close() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if !queue.closed {
+		queue.closed = true
+		close(queue.traps)
+		close(queue.done)
+	}
+}
+
+// wait waits for the given delay, unless the queue is closed in the meantime
+func (queue *deliveryQueue) wait(delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-queue.done:
+	case <-timer.C:
+	}
+}
+
+func (queue *deliveryQueue) isClosed() bool {
+	select {
+	case <-queue.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliver sends the queued traps of a destination, retrying with an exponential backoff. The failed attempts are
+// counted with the retry outcome, and the trap with the failure outcome once its retries are exhausted. Once the
+// queue is closed, the traps are not retried anymore.
+func (trapSender TrapSender) deliver(index int, queue *deliveryQueue) {
+	destination := trapSender.configuration.SNMPDestinations[index]
+	connectionArguments := trapSender.snmpConnectionArguments[index]
+
+	for trap := range queue.traps {
+		telemetry.SNMPQueueLength.WithLabelValues(destination.Address).Dec()
+		telemetry.SNMPQueueWaitSeconds.WithLabelValues(destination.Address).Observe(time.Since(trap.enqueuedAt).Seconds())
+
+		backoff := trapSender.configuration.QueueRetryBackoff
+		for attempt := uint(0); ; attempt++ {
+			lastAttempt := attempt >= trapSender.configuration.QueueRetries || queue.isClosed()
+			failureOutcome := "retry"
+			if lastAttempt {
+				failureOutcome = "failure"
+			}
+			failedTraps, err := trapSender.sendTraps(destination, connectionArguments, []snmpgo.VarBinds{trap.varBinds}, failureOutcome)
+			if err == nil {
+				break
+			}
+			if lastAttempt {
+				if !trap.spool || !trapSender.trySpoolTraps(index, failedTraps) {
+					trapSender.logger.Error("giving up sending trap", "destination", destination.Address, "attempts", attempt+1)
+				}
+				break
+			}
+			queue.wait(backoff)
+			if backoff < trapSender.configuration.QueueMaxRetryBackoff {
+				backoff = min(2*backoff, trapSender.configuration.QueueMaxRetryBackoff)
+			}
+		}
+	}
+}
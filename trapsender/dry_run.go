@@ -0,0 +1,100 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trapsender
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/k-sone/snmpgo"
+
+	"github.com/maxwo/snmp_notifier/telemetry"
+)
+
+// dryRunRecord is a line of the dry run file, describing a trap that would have been sent
+type dryRunRecord struct {
+	Time    time.Time `json:"time"`
+	Address string    `json:"address"`
+	RenderedTrap
+}
+
+// the destinations write to the dry run file concurrently
+var dryRunFileLock sync.Mutex
+
+// logTraps logs the traps of a destination, or writes them to the dry run file, instead of sending them
+func (trapSender TrapSender) logTraps(destination Destination, traps []snmpgo.VarBinds) ([]snmpgo.VarBinds, error) {
+	records := make([]dryRunRecord, 0, len(traps))
+	for _, trap := range traps {
+		records = append(records, dryRunRecord{
+			Time:         time.Now(),
+			Address:      destination.Address,
+			RenderedTrap: renderTrap(destination, trap),
+		})
+	}
+
+	if trapSender.configuration.DryRunFile != "" {
+		if err := writeDryRunRecords(trapSender.configuration.DryRunFile, records); err != nil {
+			trapSender.logger.Error("error while writing the dry run file", "destination", destination.Address, "err", err.Error())
+			return traps, err
+		}
+	} else {
+		for _, record := range records {
+			varBinds := []any{}
+			for _, varBind := range record.VarBinds {
+				varBinds = append(varBinds, slog.String(varBind.OID, varBind.Value))
+			}
+			trapSender.logger.Info("dry run, trap not sent", "destination", destination.Address, "trapOid", record.TrapOID, "groupId", record.GroupID, slog.Group("varbinds", varBinds...))
+		}
+	}
+
+	telemetry.SNMPTrapTotal.WithLabelValues(destination.Address, "dry_run").Add(float64(len(traps)))
+	return nil, nil
+}
+
+func writeDryRunRecords(fileName string, records []dryRunRecord) error {
+	dryRunFileLock.Lock()
+	defer dryRunFileLock.Unlock()
+
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
+
+// renderTrap renders the variable bindings of a trap generated by generateVarBinds
+func renderTrap(destination Destination, trap snmpgo.VarBinds) RenderedTrap {
+	rendered := RenderedTrap{
+		Destinations: []string{destination.Name},
+		VarBinds:     renderVarBinds(trap),
+	}
+	if varBind := trap.MatchOid(snmpgo.OidSnmpTrap); varBind != nil {
+		rendered.TrapOID = varBind.Variable.String()
+	}
+	// the alert ID follows the uptime and the trap OID, heartbeat traps having none
+	if len(rendered.VarBinds) > 2 && rendered.VarBinds[2].Type == "OctetString" {
+		rendered.GroupID = rendered.VarBinds[2].Value
+	}
+	return rendered
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trapsender
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/k-sone/snmpgo"
+)
+
+// HeartbeatObjectsOID is the OID of the snmpNotifierHeartbeatObjects subtree of SNMP-NOTIFIER-MIB
+const HeartbeatObjectsOID = "1.3.6.1.4.1.98789.6"
+
+// Heartbeat describes the state of the SNMP notifier sent by a heartbeat trap
+type Heartbeat struct {
+	TrapOID string
+	// Uptime is the time elapsed since the start of the SNMP notifier
+	Uptime time.Duration
+	// SentTraps and FailedTraps count the traps sent and failed since the start of the SNMP notifier
+	SentTraps   uint64
+	FailedTraps uint64
+}
+
+// SendHeartbeat sends a heartbeat trap to every destination, whatever the routes. Heartbeat traps go through the
+// delivery queues of the alert traps, so that they stop when the alert traps are stuck. They are never spooled, as
+// replaying late heartbeats would tell that a broken path works.
+func (trapSender TrapSender) SendHeartbeat(heartbeat Heartbeat) error {
+	varBinds, err := heartbeatVarBinds(heartbeat)
+	if err != nil {
+		return err
+	}
+
+	traps := make([][]snmpgo.VarBinds, len(trapSender.configuration.SNMPDestinations))
+	for index := range traps {
+		traps[index] = []snmpgo.VarBinds{varBinds}
+	}
+
+	if trapSender.IsAsynchronous() {
+		return trapSender.enqueueTraps(traps, false)
+	}
+
+	return trapSender.fanOutTraps(traps, func(index int, traps []snmpgo.VarBinds) error {
+		_, err := trapSender.sendTraps(trapSender.configuration.SNMPDestinations[index], trapSender.snmpConnectionArguments[index], traps, "failure")
+		return err
+	})
+}
+
+func heartbeatVarBinds(heartbeat Heartbeat) (snmpgo.VarBinds, error) {
+	trapOID, err := snmpgo.NewOid(heartbeat.TrapOID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid heartbeat trap OID %s: %w", heartbeat.TrapOID, err)
+	}
+
+	varBinds := addUpTime(nil)
+	varBinds = append(varBinds, snmpgo.NewVarBind(snmpgo.OidSnmpTrap, trapOID))
+	// the counters wrap around, as Counter32 objects do
+	for subOID, variable := range []snmpgo.Variable{
+		snmpgo.NewTimeTicks(uint32(heartbeat.Uptime / (10 * time.Millisecond))),
+		snmpgo.NewCounter32(uint32(heartbeat.SentTraps)),
+		snmpgo.NewCounter32(uint32(heartbeat.FailedTraps)),
+	} {
+		oid, _ := snmpgo.NewOid(fmt.Sprintf("%s.%d", HeartbeatObjectsOID, subOID+1))
+		varBinds = append(varBinds, snmpgo.NewVarBind(oid, variable))
+	}
+	return varBinds, nil
+}
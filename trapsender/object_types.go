@@ -0,0 +1,104 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trapsender
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/k-sone/snmpgo"
+)
+
+// SNMP types of the trap objects
+const (
+	StringObjectType    = "string"
+	IntegerObjectType   = "integer"
+	Counter32ObjectType = "counter32"
+	Gauge32ObjectType   = "gauge32"
+	Counter64ObjectType = "counter64"
+	TimeTicksObjectType = "timeticks"
+	IPAddressObjectType = "ipaddress"
+	OIDObjectType       = "oid"
+)
+
+// ObjectTypes lists the SNMP types available for the trap objects
+var ObjectTypes = []string{
+	StringObjectType,
+	IntegerObjectType,
+	Counter32ObjectType,
+	Gauge32ObjectType,
+	Counter64ObjectType,
+	TimeTicksObjectType,
+	IPAddressObjectType,
+	OIDObjectType,
+}
+
+func isStringObjectType(objectType string) bool {
+	return objectType == "" || objectType == StringObjectType
+}
+
+// newVariable converts a rendered template into a variable of the given SNMP type. An empty type stands for a string.
+func newVariable(objectType string, value string) (snmpgo.Variable, error) {
+	value = strings.TrimSpace(value)
+
+	switch objectType {
+	case "", StringObjectType:
+		return snmpgo.NewOctetString([]byte(value)), nil
+	case IntegerObjectType:
+		integer, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, conversionError(objectType, value, err)
+		}
+		return snmpgo.NewInteger(int32(integer)), nil
+	case Counter32ObjectType, Gauge32ObjectType, TimeTicksObjectType:
+		unsigned, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, conversionError(objectType, value, err)
+		}
+		switch objectType {
+		case Counter32ObjectType:
+			return snmpgo.NewCounter32(uint32(unsigned)), nil
+		case Gauge32ObjectType:
+			return snmpgo.NewGauge32(uint32(unsigned)), nil
+		default:
+			return snmpgo.NewTimeTicks(uint32(unsigned)), nil
+		}
+	case Counter64ObjectType:
+		unsigned, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, conversionError(objectType, value, err)
+		}
+		return snmpgo.NewCounter64(unsigned), nil
+	case IPAddressObjectType:
+		ip := net.ParseIP(value).To4()
+		if ip == nil {
+			return nil, conversionError(objectType, value, fmt.Errorf("not an IPv4 address"))
+		}
+		return snmpgo.NewIpaddress(ip[0], ip[1], ip[2], ip[3]), nil
+	case OIDObjectType:
+		oid, err := snmpgo.NewOid(strings.TrimPrefix(value, "."))
+		if err != nil {
+			return nil, conversionError(objectType, value, err)
+		}
+		return oid, nil
+	default:
+		return nil, fmt.Errorf("unknown object type: %s", objectType)
+	}
+}
+
+func conversionError(objectType string, value string, err error) error {
+	return fmt.Errorf("unable to convert %q to %s: %w", value, objectType, err)
+}
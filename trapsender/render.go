@@ -0,0 +1,118 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trapsender
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/k-sone/snmpgo"
+
+	"github.com/maxwo/snmp_notifier/commons"
+	"github.com/maxwo/snmp_notifier/types"
+)
+
+// RenderedTrap is the human-readable form of a trap, with the destinations it is routed to
+type RenderedTrap struct {
+	GroupID      string            `json:"groupId"`
+	TrapOID      string            `json:"trapOid"`
+	Destinations []string          `json:"destinations"`
+	VarBinds     []RenderedVarBind `json:"varbinds"`
+}
+
+// RenderedVarBind is the human-readable form of a variable binding of a trap
+type RenderedVarBind struct {
+	OID   string `json:"oid"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// RenderTraps generates the traps of the given alerts as they would be sent, without sending them
+func (trapSender TrapSender) RenderTraps(alertBucket types.AlertBucket) ([]RenderedTrap, error) {
+	traps := []RenderedTrap{}
+	for _, groupID := range slices.Sorted(maps.Keys(alertBucket.AlertGroups)) {
+		alertGroup := *alertBucket.AlertGroups[groupID]
+		groupTraps, err := trapSender.generateGroupTraps(groupID, alertGroup)
+		if err != nil {
+			return nil, err
+		}
+
+		destinations := []string{}
+		for _, index := range trapSender.route(alertGroup) {
+			destinations = append(destinations, trapSender.configuration.SNMPDestinations[index].Name)
+		}
+
+		for _, varBinds := range groupTraps {
+			traps = append(traps, RenderedTrap{
+				GroupID:      groupID,
+				TrapOID:      alertGroup.TrapOID,
+				Destinations: destinations,
+				VarBinds:     renderVarBinds(varBinds),
+			})
+		}
+	}
+	return traps, nil
+}
+
+// RenderObjects renders the description and the user objects of an alert group as text, the user objects being
+// indexed by their sub-OID. Unlike the traps, the objects are neither truncated nor converted to their SNMP type.
+func (trapSender TrapSender) RenderObjects(alertGroup types.AlertGroup) (string, map[int]string, error) {
+	description, err := commons.FillTemplate(alertGroup, trapSender.configuration.DescriptionTemplate)
+	if err != nil {
+		return "", nil, err
+	}
+	userObjects := make(map[int]string, len(trapSender.configuration.UserObjects))
+	for _, userObject := range trapSender.configuration.UserObjects {
+		value, err := commons.FillTemplate(alertGroup, userObject.ContentTemplate)
+		if err != nil {
+			return "", nil, err
+		}
+		userObjects[userObject.SubOID] = strings.TrimSpace(*value)
+	}
+	return strings.TrimSpace(*description), userObjects, nil
+}
+
+// String writes the trap with one variable binding per line
+func (trap RenderedTrap) String() string {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "Trap %s\n  Group: %s\n  Destinations: %s\n", trap.TrapOID, trap.GroupID, strings.Join(trap.Destinations, ", "))
+	for _, varBind := range trap.VarBinds {
+		lines := strings.Split(varBind.Value, "\n")
+		fmt.Fprintln(builder, strings.TrimRight(fmt.Sprintf("  %s = %s: %s", varBind.OID, varBind.Type, lines[0]), " "))
+		for _, line := range lines[1:] {
+			fmt.Fprintln(builder, strings.TrimRight("    "+line, " "))
+		}
+	}
+	return builder.String()
+}
+
+func renderVarBinds(varBinds snmpgo.VarBinds) []RenderedVarBind {
+	rendered := make([]RenderedVarBind, 0, len(varBinds))
+	for _, varBind := range varBinds {
+		value := varBind.Variable.String()
+		// snmpgo writes the strings that are not ASCII in hexadecimal
+		if octetString, ok := varBind.Variable.(*snmpgo.OctetString); ok && utf8.Valid(octetString.Value) {
+			value = string(octetString.Value)
+		}
+		rendered = append(rendered, RenderedVarBind{
+			OID:   varBind.Oid.String(),
+			Type:  varBind.Variable.Type(),
+			Value: value,
+		})
+	}
+	return rendered
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trapsender
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/k-sone/snmpgo"
+
+	"github.com/maxwo/snmp_notifier/types"
+)
+
+// generateGroupTraps generates the traps of an alert group, one per part when the group is split
+func (trapSender TrapSender) generateGroupTraps(uniqueTrapID string, alertGroup types.AlertGroup) ([]snmpgo.VarBinds, error) {
+	parts := trapSender.splitAlertGroup(alertGroup)
+	traps := make([]snmpgo.VarBinds, 0, len(parts))
+	for _, part := range parts {
+		varBinds, err := trapSender.generateVarBinds(uniqueTrapID, part)
+		if err != nil {
+			return nil, err
+		}
+		traps = append(traps, varBinds)
+	}
+	return traps, nil
+}
+
+// splitAlertGroup splits an alert group into parts of at most MaxAlertsPerTrap alerts. The alerts of a split group are
+// sorted by fingerprint, so that the parts hold the same alerts from one notification to the next. The groups that are
+// not split are returned unchanged, without part index and count.
+func (trapSender TrapSender) splitAlertGroup(alertGroup types.AlertGroup) []types.AlertGroup {
+	maxAlerts := trapSender.configuration.MaxAlertsPerTrap
+	if maxAlerts <= 0 || len(alertGroup.Alerts) <= maxAlerts {
+		return []types.AlertGroup{alertGroup}
+	}
+
+	alerts := slices.Clone(alertGroup.Alerts)
+	slices.SortStableFunc(alerts, func(alert, other types.Alert) int {
+		return strings.Compare(alert.Fingerprint, other.Fingerprint)
+	})
+	chunks := slices.Collect(slices.Chunk(alerts, maxAlerts))
+
+	parts := make([]types.AlertGroup, 0, len(chunks))
+	for index, chunk := range chunks {
+		part := alertGroup
+		part.Alerts = chunk
+		part.PartIndex = index + 1
+		part.PartCount = len(chunks)
+		parts = append(parts, part)
+	}
+	return parts
+}
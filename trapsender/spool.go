@@ -0,0 +1,288 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trapsender
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/k-sone/snmpgo"
+
+	"github.com/maxwo/snmp_notifier/telemetry"
+)
+
+const spooledTrapExtension = ".json"
+
+// spool persists the traps that failed to be sent, one file per trap and one directory per destination,
+// until they are replayed
+type spool struct {
+	done     chan struct{}
+	stopOnce sync.Once
+	mutexes  []sync.Mutex
+	sequence atomic.Uint64
+}
+
+// spooledTrap is the content of a spool file
+type spooledTrap struct {
+	Destination string    `json:"destination"`
+	Timestamp   time.Time `json:"timestamp"`
+	VarBinds    [][]byte  `json:"varbinds"`
+}
+
+func newSpool(destinationsCount int) *spool {
+	return &spool{
+		done:    make(chan struct{}),
+		mutexes: make([]sync.Mutex, destinationsCount),
+	}
+}
+
+// startSpool creates the spool directories, and starts replaying the traps of every destination
+func (trapSender TrapSender) startSpool() {
+	for index := range trapSender.configuration.SNMPDestinations {
+		if err := os.MkdirAll(trapSender.spoolDirectory(index), 0o750); err != nil {
+			trapSender.logger.Error("unable to create spool directory", "directory", trapSender.spoolDirectory(index), "err", err.Error())
+			continue
+		}
+		trapSender.updateSpoolLength(index)
+		go trapSender.replaySpool(index)
+	}
+}
+
+// trySpoolTraps persists the traps that failed to be sent, and tells whether they were spooled
+func (trapSender TrapSender) trySpoolTraps(index int, traps []snmpgo.VarBinds) bool {
+	if trapSender.spool == nil {
+		return false
+	}
+	if err := trapSender.spoolTraps(index, traps); err != nil {
+		trapSender.logger.Error("unable to spool traps", "destination", trapSender.configuration.SNMPDestinations[index].Address, "err", err.Error())
+		return false
+	}
+	return true
+}
+
+func (spool *spool) stop() {
+	spool.stopOnce.Do(func() {
+		close(spool.done)
+	})
+}
+
+func (trapSender TrapSender) spoolDirectory(index int) string {
+	return filepath.Join(trapSender.configuration.SpoolDirectory, url.QueryEscape(trapSender.configuration.SNMPDestinations[index].Name))
+}
+
+// spoolTraps persists the traps of a destination, dropping the oldest ones when the spool is too large
+func (trapSender TrapSender) spoolTraps(index int, traps []snmpgo.VarBinds) error {
+	destination := trapSender.configuration.SNMPDestinations[index]
+	directory := trapSender.spoolDirectory(index)
+
+	mutex := &trapSender.spool.mutexes[index]
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, trap := range traps {
+		content := spooledTrap{
+			Destination: destination.Name,
+			Timestamp:   time.Now(),
+		}
+		for _, varBind := range trap {
+			data, err := varBind.Marshal()
+			if err != nil {
+				return err
+			}
+			content.VarBinds = append(content.VarBinds, data)
+		}
+
+		data, err := json.Marshal(content)
+		if err != nil {
+			return err
+		}
+
+		// file names keep the order of the traps
+		fileName := fmt.Sprintf("%020d-%010d", content.Timestamp.UnixNano(), trapSender.spool.sequence.Add(1))
+		temporaryFile := filepath.Join(directory, fileName+".tmp")
+		if err := os.WriteFile(temporaryFile, data, 0o640); err != nil {
+			return err
+		}
+		if err := os.Rename(temporaryFile, filepath.Join(directory, fileName+spooledTrapExtension)); err != nil {
+			return err
+		}
+	}
+
+	if trapSender.configuration.SpoolMaxSize > 0 {
+		files, err := listSpooledTraps(directory)
+		if err != nil {
+			return err
+		}
+		size := int64(0)
+		for _, file := range files {
+			size += file.size
+		}
+		for _, file := range files {
+			if size <= trapSender.configuration.SpoolMaxSize {
+				break
+			}
+			trapSender.dropSpooledTrap(destination, file.path, "max_size")
+			size -= file.size
+		}
+	}
+
+	trapSender.updateSpoolLengthLocked(index)
+	return nil
+}
+
+// replaySpool periodically sends the spooled traps of a destination in order, until one of them fails
+func (trapSender TrapSender) replaySpool(index int) {
+	ticker := time.NewTicker(trapSender.configuration.SpoolReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-trapSender.spool.done:
+			return
+		case <-ticker.C:
+			trapSender.replaySpooledTraps(index)
+		}
+	}
+}
+
+func (trapSender TrapSender) replaySpooledTraps(index int) {
+	destination := trapSender.configuration.SNMPDestinations[index]
+	connectionArguments := trapSender.snmpConnectionArguments[index]
+	mutex := &trapSender.spool.mutexes[index]
+
+	mutex.Lock()
+	files, err := listSpooledTraps(trapSender.spoolDirectory(index))
+	mutex.Unlock()
+	if err != nil {
+		trapSender.logger.Error("error while reading spool", "destination", destination.Address, "err", err.Error())
+		return
+	}
+
+	for _, file := range files {
+		select {
+		case <-trapSender.spool.done:
+			return
+		default:
+		}
+
+		trap, timestamp, err := readSpooledTrap(file.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			trapSender.logger.Error("invalid spooled trap", "destination", destination.Address, "file", file.path, "err", err.Error())
+			trapSender.removeSpooledTrap(index, func() { trapSender.dropSpooledTrap(destination, file.path, "invalid") })
+			continue
+		}
+
+		if trapSender.configuration.SpoolMaxAge > 0 && time.Since(timestamp) > trapSender.configuration.SpoolMaxAge {
+			trapSender.removeSpooledTrap(index, func() { trapSender.dropSpooledTrap(destination, file.path, "max_age") })
+			continue
+		}
+
+		// the trap was counted as failed once spooled, the failed replays are counted apart
+		if _, err := trapSender.sendTraps(destination, connectionArguments, []snmpgo.VarBinds{trap}, "retry"); err != nil {
+			// the destination is still unreachable, the next traps are kept to preserve their order
+			return
+		}
+		trapSender.removeSpooledTrap(index, func() { os.Remove(file.path) })
+	}
+}
+
+func (trapSender TrapSender) removeSpooledTrap(index int, remove func()) {
+	mutex := &trapSender.spool.mutexes[index]
+	mutex.Lock()
+	defer mutex.Unlock()
+	remove()
+	trapSender.updateSpoolLengthLocked(index)
+}
+
+func (trapSender TrapSender) dropSpooledTrap(destination Destination, path string, reason string) {
+	if err := os.Remove(path); err == nil {
+		trapSender.logger.Warn("dropping spooled trap", "destination", destination.Address, "reason", reason)
+		telemetry.SNMPSpoolDroppedTotal.WithLabelValues(destination.Address, reason).Inc()
+	}
+}
+
+func (trapSender TrapSender) updateSpoolLength(index int) {
+	mutex := &trapSender.spool.mutexes[index]
+	mutex.Lock()
+	defer mutex.Unlock()
+	trapSender.updateSpoolLengthLocked(index)
+}
+
+func (trapSender TrapSender) updateSpoolLengthLocked(index int) {
+	files, err := listSpooledTraps(trapSender.spoolDirectory(index))
+	if err != nil {
+		return
+	}
+	telemetry.SNMPSpoolLength.WithLabelValues(trapSender.configuration.SNMPDestinations[index].Address).Set(float64(len(files)))
+}
+
+type spoolFile struct {
+	path string
+	size int64
+}
+
+// listSpooledTraps returns the spooled traps of a directory, from the oldest to the newest
+func listSpooledTraps(directory string) ([]spoolFile, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []spoolFile{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), spooledTrapExtension) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, spoolFile{path: filepath.Join(directory, entry.Name()), size: info.Size()})
+	}
+	return files, nil
+}
+
+func readSpooledTrap(path string) (snmpgo.VarBinds, time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	content := spooledTrap{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	varBinds := snmpgo.VarBinds{}
+	for _, data := range content.VarBinds {
+		varBind := snmpgo.VarBind{}
+		if _, err := varBind.Unmarshal(data); err != nil {
+			return nil, time.Time{}, err
+		}
+		varBinds = append(varBinds, &varBind)
+	}
+	return varBinds, content.Timestamp, nil
+}
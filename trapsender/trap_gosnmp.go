@@ -0,0 +1,182 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trapsender
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/k-sone/snmpgo"
+
+	"github.com/maxwo/snmp_notifier/telemetry"
+)
+
+var (
+	// snmpgo only implements the original USM protocols of RFC 3414 and RFC 3826
+	snmpgoAuthenticationProtocols = []string{"MD5", "SHA"}
+	snmpgoPrivateProtocols        = []string{"DES", "AES"}
+
+	goSNMPAuthenticationProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+		"MD5":    gosnmp.MD5,
+		"SHA":    gosnmp.SHA,
+		"SHA224": gosnmp.SHA224,
+		"SHA256": gosnmp.SHA256,
+		"SHA384": gosnmp.SHA384,
+		"SHA512": gosnmp.SHA512,
+	}
+	goSNMPPrivateProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+		"DES":     gosnmp.DES,
+		"AES":     gosnmp.AES,
+		"AES192":  gosnmp.AES192,
+		"AES256":  gosnmp.AES256,
+		"AES192C": gosnmp.AES192C,
+		"AES256C": gosnmp.AES256C,
+	}
+)
+
+// requiresGoSNMP tells whether a destination uses SNMPv3 security protocols that snmpgo does not implement
+func requiresGoSNMP(destination Destination) bool {
+	if destination.Version != "V3" {
+		return false
+	}
+	if destination.AuthenticationEnabled && !slices.Contains(snmpgoAuthenticationProtocols, destination.AuthenticationProtocol) {
+		return true
+	}
+	return destination.PrivateEnabled && !slices.Contains(snmpgoPrivateProtocols, destination.PrivateProtocol)
+}
+
+func (trapSender TrapSender) sendGoSNMPTraps(destination Destination, traps []snmpgo.VarBinds) ([]snmpgo.VarBinds, error) {
+	distinationForMetrics := destination.Address
+
+	snmp, err := newGoSNMP(destination, int(time.Now().Unix())-trapSender.configuration.SNMPEngineStartTimeUnix)
+	if err == nil {
+		err = snmp.Connect()
+	}
+	if err != nil {
+		trapSender.logger.Error("error while opening SNMP connection", "err", err.Error())
+		return traps, err
+	}
+
+	defer func() {
+		snmp.Conn.Close()
+	}()
+
+	failedTraps := []snmpgo.VarBinds{}
+	for _, trap := range traps {
+		_, err = snmp.SendTrap(gosnmp.SnmpTrap{
+			Variables: toSnmpPDUs(trap),
+			IsInform:  destination.Inform,
+		})
+		if err != nil {
+			trapSender.logger.Error("error while sending trap", "destination", distinationForMetrics, "inform", destination.Inform, "err", err.Error())
+			failedTraps = append(failedTraps, trap)
+			continue
+		}
+		telemetry.SNMPTrapTotal.WithLabelValues(distinationForMetrics, "success").Inc()
+	}
+
+	if len(failedTraps) > 0 {
+		return failedTraps, errors.New("error while sending one or more traps")
+	}
+	return nil, nil
+}
+
+func newGoSNMP(destination Destination, engineTime int) (*gosnmp.GoSNMP, error) {
+	host, portString, err := net.SplitHostPort(destination.Address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port for destination %s: %w", destination.Address, err)
+	}
+
+	securityEngineID, err := hex.DecodeString(destination.SecurityEngineID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid security engine ID for destination %s: %w", destination.Address, err)
+	}
+	contextEngineID, err := hex.DecodeString(destination.ContextEngineID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid context engine ID for destination %s: %w", destination.Address, err)
+	}
+
+	securityParameters := &gosnmp.UsmSecurityParameters{
+		UserName:                 destination.AuthenticationUsername,
+		AuthoritativeEngineID:    string(securityEngineID),
+		AuthoritativeEngineTime:  uint32(max(engineTime, 0)),
+		AuthenticationProtocol:   gosnmp.NoAuth,
+		PrivacyProtocol:          gosnmp.NoPriv,
+		AuthenticationPassphrase: destination.AuthenticationPassword,
+		PrivacyPassphrase:        destination.PrivatePassword,
+	}
+
+	messageFlags := gosnmp.NoAuthNoPriv
+	if destination.AuthenticationEnabled {
+		messageFlags = gosnmp.AuthNoPriv
+		securityParameters.AuthenticationProtocol = goSNMPAuthenticationProtocols[destination.AuthenticationProtocol]
+	}
+	if destination.PrivateEnabled {
+		messageFlags = gosnmp.AuthPriv
+		securityParameters.PrivacyProtocol = goSNMPPrivateProtocols[destination.PrivateProtocol]
+	}
+
+	return &gosnmp.GoSNMP{
+		Target:             host,
+		Port:               uint16(port),
+		Transport:          "udp",
+		Version:            gosnmp.Version3,
+		Timeout:            destination.Timeout,
+		Retries:            int(destination.Retries),
+		MsgFlags:           messageFlags,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		SecurityParameters: securityParameters,
+		ContextEngineID:    string(contextEngineID),
+		ContextName:        destination.ContextName,
+	}, nil
+}
+
+func toSnmpPDUs(varBinds snmpgo.VarBinds) []gosnmp.SnmpPDU {
+	pdus := make([]gosnmp.SnmpPDU, 0, len(varBinds))
+	for _, varBind := range varBinds {
+		pdu := gosnmp.SnmpPDU{Name: varBind.Oid.String()}
+		switch variable := varBind.Variable.(type) {
+		case *snmpgo.OctetString:
+			pdu.Type, pdu.Value = gosnmp.OctetString, variable.Value
+		case *snmpgo.Oid:
+			pdu.Type, pdu.Value = gosnmp.ObjectIdentifier, variable.String()
+		case *snmpgo.Integer:
+			pdu.Type, pdu.Value = gosnmp.Integer, int(variable.Value)
+		case *snmpgo.Counter32:
+			pdu.Type, pdu.Value = gosnmp.Counter32, variable.Value
+		case *snmpgo.Gauge32:
+			pdu.Type, pdu.Value = gosnmp.Gauge32, variable.Value
+		case *snmpgo.Counter64:
+			pdu.Type, pdu.Value = gosnmp.Counter64, variable.Value
+		case *snmpgo.TimeTicks:
+			pdu.Type, pdu.Value = gosnmp.TimeTicks, variable.Value
+		case *snmpgo.Ipaddress:
+			pdu.Type, pdu.Value = gosnmp.IPAddress, variable.String()
+		default:
+			pdu.Type, pdu.Value = gosnmp.Null, nil
+		}
+		pdus = append(pdus, pdu)
+	}
+	return pdus
+}
@@ -15,9 +15,13 @@ package trapsender
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/maxwo/snmp_notifier/commons"
@@ -27,141 +31,489 @@ import (
 	"text/template"
 
 	"github.com/k-sone/snmpgo"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/common/model"
 	"github.com/shirou/gopsutil/host"
 )
 
+var errSendTimeout = errors.New("traps not sent within the send timeout")
+
 // TrapSender sends traps according to given alerts
 type TrapSender struct {
 	logger                  *slog.Logger
 	configuration           Configuration
 	snmpConnectionArguments []snmpgo.SNMPArguments
+	destinationIndexes      map[string]int
+	queues                  []*deliveryQueue
+	spool                   *spool
+	// users counts the users sending traps, which Stop waits for
+	users *sync.WaitGroup
 }
 
 // Configuration describes the configuration for sending traps
 type Configuration struct {
-	SNMPDestination         []string
-	SNMPRetries             uint
-	SNMPVersion             string
-	SNMPTimeout             time.Duration
+	SNMPDestinations        []Destination
 	SNMPEngineStartTimeUnix int
 
-	SNMPCommunity string
+	Routes              []Route
+	DefaultDestinations []string
+
+	QueueSize         int
+	QueueWorkers      int
+	QueueRetries      uint
+	QueueRetryBackoff time.Duration
+	// QueueMaxRetryBackoff caps the delay between two retries, doubled after each one. The delay stays the same when 0.
+	QueueMaxRetryBackoff time.Duration
+
+	SendTimeout time.Duration
+
+	// MaxVarBindSize and MaxPDUSize limit the size of the string objects and of the PDU of the traps, in bytes,
+	// the description being truncated to fit. Unlimited when 0.
+	MaxVarBindSize int
+	MaxPDUSize     int
+
+	// MaxAlertsPerTrap splits the alert groups with more alerts into several traps, carrying their part index and
+	// count. Unlimited when 0.
+	MaxAlertsPerTrap int
+
+	// DryRun logs the traps, or writes them to DryRunFile as JSON lines, instead of sending them
+	DryRun     bool
+	DryRunFile string
 
-	SNMPAuthenticationEnabled  bool
-	SNMPAuthenticationProtocol string
-	SNMPAuthenticationUsername string
-	SNMPAuthenticationPassword string
-	SNMPPrivateEnabled         bool
-	SNMPPrivateProtocol        string
-	SNMPPrivatePassword        string
-	SNMPSecurityEngineID       string
-	SNMPContextEngineID        string
-	SNMPContextName            string
+	SpoolDirectory      string
+	SpoolMaxAge         time.Duration
+	SpoolMaxSize        int64
+	SpoolReplayInterval time.Duration
 
 	DescriptionTemplate template.Template
+	DescriptionType     string
 	UserObjects         []UserObject
 }
 
+// Destination describes an SNMP server receiving traps, with its own version and credentials
+type Destination struct {
+	Name    string
+	Address string
+	Version string
+	Retries uint
+	Timeout time.Duration
+	Inform  bool
+
+	Community    string
+	AgentAddress string
+
+	AuthenticationEnabled  bool
+	AuthenticationProtocol string
+	AuthenticationUsername string
+	AuthenticationPassword string
+	PrivateEnabled         bool
+	PrivateProtocol        string
+	PrivatePassword        string
+	SecurityEngineID       string
+	ContextEngineID        string
+	ContextName            string
+}
+
+// Route sends the traps of the alert groups matching a receiver and label matchers to the given destinations
+type Route struct {
+	Receiver     string
+	Matchers     labels.Matchers
+	Destinations []string
+}
+
 // UserObject describes a custom field sent via SNMP
 type UserObject struct {
 	SubOID          int
+	Type            string
 	ContentTemplate template.Template
+	// Name and Description describe the object in the generated MIB module
+	Name        string
+	Description string
 }
 
 // New creates a new TrapSender
 func New(configuration Configuration, logger *slog.Logger) TrapSender {
 	snmpConnectionArguments := generationConnectionArguments(configuration)
-	return TrapSender{logger, configuration, snmpConnectionArguments}
+	destinationIndexes := make(map[string]int)
+	for index, destination := range configuration.SNMPDestinations {
+		destinationIndexes[destination.Name] = index
+	}
+	trapSender := TrapSender{
+		logger:                  logger,
+		configuration:           configuration,
+		snmpConnectionArguments: snmpConnectionArguments,
+		destinationIndexes:      destinationIndexes,
+		users:                   &sync.WaitGroup{},
+	}
+	// the traps of a dry run never fail to be sent, nor replay the spooled ones
+	if configuration.SpoolDirectory != "" && !configuration.DryRun {
+		trapSender.spool = newSpool(len(configuration.SNMPDestinations))
+		trapSender.startSpool()
+	}
+	if configuration.QueueSize > 0 {
+		trapSender.queues = trapSender.startDeliveryQueues()
+	}
+	return trapSender
+}
+
+// IsAsynchronous tells whether traps are queued and sent in the background
+func (trapSender TrapSender) IsAsynchronous() bool {
+	return trapSender.queues != nil
+}
+
+// Acquire marks the trap sender as used until Release is called, so that Stop waits for the traps being sent.
+// The users must acquire the trap sender before it is replaced, e.g. while holding the lock guarding it.
+func (trapSender TrapSender) Acquire() {
+	if trapSender.users != nil {
+		trapSender.users.Add(1)
+	}
+}
+
+// Release marks the end of a use of the trap sender
+func (trapSender TrapSender) Release() {
+	if trapSender.users != nil {
+		trapSender.users.Done()
+	}
+}
+
+// Stop waits for the users of the trap sender, then stops accepting traps and replaying spooled traps.
+// The traps already queued are still sent.
+func (trapSender TrapSender) Stop() {
+	if trapSender.users != nil {
+		trapSender.users.Wait()
+	}
+	for _, queue := range trapSender.queues {
+		queue.close()
+	}
+	if trapSender.spool != nil {
+		trapSender.spool.stop()
+	}
 }
 
 // SendAlertTraps sends a bucket of alerts to the given SNMP connection
 func (trapSender TrapSender) SendAlertTraps(alertBucket types.AlertBucket) error {
 	traps, err := trapSender.generateTraps(alertBucket)
 	if err != nil {
-		for _, connection := range trapSender.snmpConnectionArguments {
-			telemetry.SNMPTrapTotal.WithLabelValues(connection.Address, "failure").Add(float64(len(traps)))
+		for _, alertGroup := range alertBucket.AlertGroups {
+			for _, index := range trapSender.route(*alertGroup) {
+				telemetry.SNMPTrapTotal.WithLabelValues(trapSender.snmpConnectionArguments[index].Address, "failure").Inc()
+			}
 		}
 		return err
 	}
 
-	hasError := false
+	if trapSender.IsAsynchronous() {
+		return trapSender.enqueueTraps(traps, true)
+	}
+
+	return trapSender.fanOutTraps(traps, trapSender.sendDestinationTraps)
+}
+
+// fanOutTraps sends the traps of every destination concurrently with the given function, and reports the
+// destinations that failed, or that were still being sent once the send timeout elapsed
+func (trapSender TrapSender) fanOutTraps(traps [][]snmpgo.VarBinds, send func(index int, traps []snmpgo.VarBinds) error) error {
+	type outcome struct {
+		index int
+		err   error
+	}
+
+	// buffered, so that the destinations completing after the send timeout do not block
+	outcomes := make(chan outcome, len(traps))
+	pending := map[int]bool{}
+	for index, destinationTraps := range traps {
+		if len(destinationTraps) == 0 {
+			continue
+		}
+		pending[index] = true
+		go func() {
+			outcomes <- outcome{index: index, err: send(index, destinationTraps)}
+		}()
+	}
+
+	var deadline <-chan time.Time
+	if trapSender.configuration.SendTimeout > 0 {
+		timer := time.NewTimer(trapSender.configuration.SendTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	destinationErrors := make([]error, len(traps))
+	for len(pending) > 0 {
+		select {
+		case outcome := <-outcomes:
+			delete(pending, outcome.index)
+			destinationErrors[outcome.index] = outcome.err
+		case <-deadline:
+			for index := range pending {
+				trapSender.logger.Error("traps still being sent after the send timeout", "destination", trapSender.configuration.SNMPDestinations[index].Address)
+				destinationErrors[index] = errSendTimeout
+			}
+			pending = nil
+		}
+	}
 
-	for _, connection := range trapSender.snmpConnectionArguments {
-		err := trapSender.sendTraps(connection, traps)
+	errs := []error{}
+	for index, err := range destinationErrors {
 		if err != nil {
-			hasError = true
+			errs = append(errs, fmt.Errorf("destination %s: %w", trapSender.configuration.SNMPDestinations[index].Name, err))
 		}
 	}
+	return errors.Join(errs...)
+}
 
-	if hasError {
-		return errors.New("error while sending one or more traps")
+// sendDestinationTraps sends the traps of a destination, and spools the ones that failed
+func (trapSender TrapSender) sendDestinationTraps(index int, traps []snmpgo.VarBinds) error {
+	failedTraps, err := trapSender.sendTraps(trapSender.configuration.SNMPDestinations[index], trapSender.snmpConnectionArguments[index], traps, "failure")
+	if err != nil && !trapSender.trySpoolTraps(index, failedTraps) {
+		return err
 	}
 	return nil
 }
 
-func (trapSender TrapSender) sendTraps(connectionArguments snmpgo.SNMPArguments, traps []snmpgo.VarBinds) error {
+// enqueueTraps queues the traps of each destination, spooled once their retries failed if spool is set, and fails
+// if a queue is full
+func (trapSender TrapSender) enqueueTraps(traps [][]snmpgo.VarBinds, spool bool) error {
+	fullQueues := []string{}
+	for index, destinationTraps := range traps {
+		destination := trapSender.configuration.SNMPDestinations[index]
+		dropped := 0
+		for _, trap := range destinationTraps {
+			if trapSender.queues[index].enqueue(trap, spool) {
+				telemetry.SNMPQueueLength.WithLabelValues(destination.Address).Inc()
+			} else {
+				dropped++
+			}
+		}
+		if dropped > 0 {
+			trapSender.logger.Error("queue full, dropping traps", "destination", destination.Address, "count", dropped)
+			telemetry.SNMPTrapTotal.WithLabelValues(destination.Address, "dropped").Add(float64(dropped))
+			fullQueues = append(fullQueues, destination.Name)
+		}
+	}
+
+	if len(fullQueues) > 0 {
+		return fmt.Errorf("queue full for destinations: %s", strings.Join(fullQueues, ", "))
+	}
+	return nil
+}
+
+// route returns the indexes of the destinations the traps of an alert group are sent to
+func (trapSender TrapSender) route(alertGroup types.AlertGroup) []int {
+	for _, route := range trapSender.configuration.Routes {
+		if route.matches(alertGroup) {
+			return trapSender.getDestinationIndexes(route.Destinations)
+		}
+	}
+
+	if len(trapSender.configuration.DefaultDestinations) > 0 {
+		return trapSender.getDestinationIndexes(trapSender.configuration.DefaultDestinations)
+	}
+
+	indexes := make([]int, len(trapSender.configuration.SNMPDestinations))
+	for index := range indexes {
+		indexes[index] = index
+	}
+	return indexes
+}
+
+func (trapSender TrapSender) getDestinationIndexes(names []string) []int {
+	indexes := []int{}
+	for _, name := range names {
+		if index, found := trapSender.destinationIndexes[name]; found {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+func (route Route) matches(alertGroup types.AlertGroup) bool {
+	if route.Receiver != "" && route.Receiver != alertGroup.Receiver {
+		return false
+	}
+
+	labelSet := model.LabelSet{}
+	for name, value := range alertGroup.GroupLabels {
+		labelSet[model.LabelName(name)] = model.LabelValue(value)
+	}
+	for name, value := range alertGroup.CommonLabels {
+		labelSet[model.LabelName(name)] = model.LabelValue(value)
+	}
+	return route.Matchers.Matches(labelSet)
+}
+
+// sendTraps sends the traps of a destination, and counts the failed ones with the given outcome: failure once they
+// are given up or spooled, retry when they are retried later
+func (trapSender TrapSender) sendTraps(destination Destination, connectionArguments snmpgo.SNMPArguments, traps []snmpgo.VarBinds, failureOutcome string) ([]snmpgo.VarBinds, error) {
+	failedTraps, err := trapSender.transmitTraps(destination, connectionArguments, traps)
+	if len(failedTraps) > 0 {
+		telemetry.SNMPTrapTotal.WithLabelValues(destination.Address, failureOutcome).Add(float64(len(failedTraps)))
+	}
+	return failedTraps, err
+}
+
+// transmitTraps sends the traps of a destination with the protocol of its version, and returns the failed ones
+func (trapSender TrapSender) transmitTraps(destination Destination, connectionArguments snmpgo.SNMPArguments, traps []snmpgo.VarBinds) ([]snmpgo.VarBinds, error) {
+	if trapSender.configuration.DryRun {
+		return trapSender.logTraps(destination, traps)
+	}
+
+	if destination.Version == "V1" {
+		return trapSender.sendV1Traps(destination, traps)
+	}
+
+	if requiresGoSNMP(destination) {
+		return trapSender.sendGoSNMPTraps(destination, traps)
+	}
+
 	distinationForMetrics := connectionArguments.Address
 
 	snmp, err := snmpgo.NewSNMP(connectionArguments)
 	if err != nil {
 		trapSender.logger.Error("error while creating SNMP connection", "err", err.Error())
-		telemetry.SNMPTrapTotal.WithLabelValues(distinationForMetrics, "failure").Add(float64(len(traps)))
-		return err
+		return traps, err
 	}
 
 	err = snmp.Open()
 	if err != nil {
 		trapSender.logger.Error("error while opening SNMP connection", "err", err.Error())
-		telemetry.SNMPTrapTotal.WithLabelValues(distinationForMetrics, "failure").Add(float64(len(traps)))
-		return err
+		return traps, err
 	}
 
 	defer func() {
 		snmp.Close()
 	}()
 
-	hasError := false
+	failedTraps := []snmpgo.VarBinds{}
 	for _, trap := range traps {
-		err = snmp.V2TrapWithBootsTime(trap, 0, int(time.Now().Unix())-trapSender.configuration.SNMPEngineStartTimeUnix)
+		if destination.Inform {
+			// the request is retried until acknowledged, according to the destination retries and timeout
+			err = snmp.InformRequest(trap)
+		} else {
+			err = snmp.V2TrapWithBootsTime(trap, 0, int(time.Now().Unix())-trapSender.configuration.SNMPEngineStartTimeUnix)
+		}
 		if err != nil {
-			telemetry.SNMPTrapTotal.WithLabelValues(distinationForMetrics, "failure").Inc()
-			trapSender.logger.Error("error while generating trap", "destination", distinationForMetrics, "err", err.Error())
-			hasError = true
+			trapSender.logger.Error("error while sending trap", "destination", distinationForMetrics, "inform", destination.Inform, "err", err.Error())
+			failedTraps = append(failedTraps, trap)
+			continue
 		}
 		telemetry.SNMPTrapTotal.WithLabelValues(distinationForMetrics, "success").Inc()
 	}
 
-	if hasError {
-		return errors.New("error while sending one or more traps")
+	if len(failedTraps) > 0 {
+		return failedTraps, errors.New("error while sending one or more traps")
 	}
-	return nil
+	return nil, nil
 }
 
-func (trapSender TrapSender) generateTraps(alertBucket types.AlertBucket) ([]snmpgo.VarBinds, error) {
-	var (
-		traps []snmpgo.VarBinds
-	)
+// generateTraps generates the traps of the given alerts, indexed by the destination they are routed to
+func (trapSender TrapSender) generateTraps(alertBucket types.AlertBucket) ([][]snmpgo.VarBinds, error) {
+	traps := make([][]snmpgo.VarBinds, len(trapSender.snmpConnectionArguments))
 	for uniqueTrapID, alertGroup := range alertBucket.AlertGroups {
-		varBinds, err := trapSender.generateVarBinds(uniqueTrapID, *alertGroup)
+		groupTraps, err := trapSender.generateGroupTraps(uniqueTrapID, *alertGroup)
 		if err != nil {
 			return nil, err
 		}
 
-		traps = append(traps, varBinds)
+		for _, index := range trapSender.route(*alertGroup) {
+			traps[index] = append(traps[index], groupTraps...)
+		}
 	}
 	return traps, nil
 }
 
 func (trapSender TrapSender) generateVarBinds(uniqueTrapID string, alertGroup types.AlertGroup) (snmpgo.VarBinds, error) {
-	var (
-		varBinds snmpgo.VarBinds
-	)
-
 	description, err := commons.FillTemplate(alertGroup, trapSender.configuration.DescriptionTemplate)
 	if err != nil {
 		return nil, err
 	}
 
+	userObjectValues := make([]string, len(trapSender.configuration.UserObjects))
+	truncated := false
+	for index, userObject := range trapSender.configuration.UserObjects {
+		value, err := commons.FillTemplate(alertGroup, userObject.ContentTemplate)
+		if err != nil {
+			return nil, err
+		}
+		userObjectValues[index] = *value
+		if isStringObjectType(userObject.Type) && trapSender.exceedsMaxVarBindSize(*value) {
+			userObjectValues[index] = truncateString(strings.TrimSpace(*value), trapSender.configuration.MaxVarBindSize, truncationSuffix)
+			truncated = true
+		}
+	}
+
+	varBinds, err := trapSender.newVarBinds(uniqueTrapID, alertGroup, *description, userObjectValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if isStringObjectType(trapSender.configuration.DescriptionType) {
+		size, err := trapSender.maxDescriptionSize(*description, varBinds)
+		if err != nil {
+			return nil, err
+		}
+		if size >= 0 {
+			truncatedDescription, err := trapSender.truncateDescription(alertGroup, size)
+			if err != nil {
+				return nil, err
+			}
+			varBinds, err = trapSender.newVarBinds(uniqueTrapID, alertGroup, truncatedDescription, userObjectValues)
+			if err != nil {
+				return nil, err
+			}
+			truncated = true
+		}
+	}
+
+	if trapSender.configuration.MaxPDUSize > 0 {
+		size, err := pduSize(varBinds)
+		if err != nil {
+			return nil, err
+		}
+		if size > trapSender.configuration.MaxPDUSize {
+			return nil, fmt.Errorf("trap %s exceeds the maximum PDU size of %d bytes: %d bytes", uniqueTrapID, trapSender.configuration.MaxPDUSize, size)
+		}
+	}
+
+	if truncated {
+		trapSender.logger.Warn("trap objects truncated to fit the maximum sizes", "trap", uniqueTrapID)
+		telemetry.SNMPTruncatedTrapsTotal.Inc()
+	}
+	return varBinds, nil
+}
+
+// maxDescriptionSize returns the size the description must be truncated to, so that it fits the maximum varbind and
+// PDU sizes, or -1 when it fits already
+func (trapSender TrapSender) maxDescriptionSize(description string, varBinds snmpgo.VarBinds) (int, error) {
+	description = strings.TrimSpace(description)
+	size := -1
+	if trapSender.exceedsMaxVarBindSize(description) {
+		size = trapSender.configuration.MaxVarBindSize
+	}
+
+	if trapSender.configuration.MaxPDUSize > 0 {
+		currentPDUSize, err := pduSize(varBinds)
+		if err != nil {
+			return 0, err
+		}
+		if excess := currentPDUSize - trapSender.configuration.MaxPDUSize; excess > 0 {
+			if size < 0 || len(description)-excess < size {
+				size = max(len(description)-excess, 0)
+			}
+		}
+	}
+	return size, nil
+}
+
+func (trapSender TrapSender) exceedsMaxVarBindSize(value string) bool {
+	return trapSender.configuration.MaxVarBindSize > 0 && len(strings.TrimSpace(value)) > trapSender.configuration.MaxVarBindSize
+}
+
+func (trapSender TrapSender) newVarBinds(uniqueTrapID string, alertGroup types.AlertGroup, description string, userObjectValues []string) (snmpgo.VarBinds, error) {
+	var (
+		varBinds snmpgo.VarBinds
+		err      error
+	)
+
 	baseOid := alertGroup.DefaultObjectsBaseOID
 	userObjectsBaseOID := alertGroup.UserObjectsBaseOID
 	trapOid, _ := snmpgo.NewOid(alertGroup.TrapOID)
@@ -170,19 +522,64 @@ func (trapSender TrapSender) generateVarBinds(uniqueTrapID string, alertGroup ty
 	varBinds = append(varBinds, snmpgo.NewVarBind(snmpgo.OidSnmpTrap, trapOid))
 	varBinds = addTrapSubObject(varBinds, baseOid, 1, uniqueTrapID)
 	varBinds = addTrapSubObject(varBinds, baseOid, 2, alertGroup.Severity)
-	varBinds = addTrapSubObject(varBinds, baseOid, 3, *description)
+	varBinds, err = addTypedTrapSubObject(varBinds, baseOid, 3, trapSender.configuration.DescriptionType, description)
+	if err != nil {
+		return nil, fmt.Errorf("invalid description: %w", err)
+	}
+	varBinds = addTrapSubObject(varBinds, baseOid, 4, alertGroup.GroupKey)
+	varBinds = addIntegerTrapSubObject(varBinds, baseOid, 5, alertGroup.SeverityLevel)
+	if alertGroup.PartCount > 0 {
+		varBinds = addIntegerTrapSubObject(varBinds, baseOid, 6, alertGroup.PartIndex)
+		varBinds = addIntegerTrapSubObject(varBinds, baseOid, 7, alertGroup.PartCount)
+	}
 
-	for _, userObject := range trapSender.configuration.UserObjects {
-		value, err := commons.FillTemplate(alertGroup, userObject.ContentTemplate)
+	for index, userObject := range trapSender.configuration.UserObjects {
+		varBinds, err = addTypedTrapSubObject(varBinds, userObjectsBaseOID, userObject.SubOID, userObject.Type, userObjectValues[index])
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid user object %d: %w", userObject.SubOID, err)
 		}
-		varBinds = addTrapSubObject(varBinds, userObjectsBaseOID, userObject.SubOID, *value)
 	}
 
 	return varBinds, nil
 }
 
+// CheckTemplates renders the description and user object templates for every alert group of a bucket,
+// and reports all the errors, once per template
+func CheckTemplates(configuration Configuration, alertBucket types.AlertBucket) error {
+	var (
+		errs     []error
+		reported = make(map[string]bool)
+	)
+	report := func(err error) {
+		if !reported[err.Error()] {
+			reported[err.Error()] = true
+			errs = append(errs, err)
+		}
+	}
+
+	for _, groupID := range slices.Sorted(maps.Keys(alertBucket.AlertGroups)) {
+		alertGroup := *alertBucket.AlertGroups[groupID]
+		if err := checkTemplate(alertGroup, configuration.DescriptionTemplate, configuration.DescriptionType); err != nil {
+			report(fmt.Errorf("invalid description template %s: %w", configuration.DescriptionTemplate.Name(), err))
+		}
+		for _, userObject := range configuration.UserObjects {
+			if err := checkTemplate(alertGroup, userObject.ContentTemplate, userObject.Type); err != nil {
+				report(fmt.Errorf("invalid template %s of user object %d: %w", userObject.ContentTemplate.Name(), userObject.SubOID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkTemplate(alertGroup types.AlertGroup, contentTemplate template.Template, objectType string) error {
+	value, err := commons.FillTemplate(alertGroup, contentTemplate)
+	if err != nil {
+		return err
+	}
+	_, err = newVariable(objectType, *value)
+	return err
+}
+
 func addUpTime(varBinds snmpgo.VarBinds) snmpgo.VarBinds {
 	uptime, _ := host.Uptime()
 	return append(varBinds, snmpgo.NewVarBind(snmpgo.OidSysUpTime, snmpgo.NewTimeTicks(uint32(uptime*100))))
@@ -194,45 +591,61 @@ func addTrapSubObject(varBinds snmpgo.VarBinds, baseOid string, subOid int, valu
 	return append(varBinds, snmpgo.NewVarBind(oid, snmpgo.NewOctetString([]byte(strings.TrimSpace(value)))))
 }
 
+func addIntegerTrapSubObject(varBinds snmpgo.VarBinds, baseOid string, subOid int, value int) snmpgo.VarBinds {
+	oidString := strings.Join([]string{baseOid, strconv.Itoa(subOid)}, ".")
+	oid, _ := snmpgo.NewOid(oidString)
+	return append(varBinds, snmpgo.NewVarBind(oid, snmpgo.NewInteger(int32(value))))
+}
+
+func addTypedTrapSubObject(varBinds snmpgo.VarBinds, baseOid string, subOid int, objectType string, value string) (snmpgo.VarBinds, error) {
+	variable, err := newVariable(objectType, value)
+	if err != nil {
+		return nil, err
+	}
+	oidString := strings.Join([]string{baseOid, strconv.Itoa(subOid)}, ".")
+	oid, _ := snmpgo.NewOid(oidString)
+	return append(varBinds, snmpgo.NewVarBind(oid, variable)), nil
+}
+
 func generationConnectionArguments(configuration Configuration) []snmpgo.SNMPArguments {
 	snmpArguments := []snmpgo.SNMPArguments{}
-	for _, destination := range configuration.SNMPDestination {
+	for _, destination := range configuration.SNMPDestinations {
 		snmpArgument := snmpgo.SNMPArguments{
-			Address: destination,
-			Retries: configuration.SNMPRetries,
-			Timeout: configuration.SNMPTimeout,
+			Address: destination.Address,
+			Retries: destination.Retries,
+			Timeout: destination.Timeout,
 		}
 
-		if configuration.SNMPVersion == "V2c" {
+		if destination.Version == "V2c" {
 			snmpArgument.Version = snmpgo.V2c
-			snmpArgument.Community = configuration.SNMPCommunity
+			snmpArgument.Community = destination.Community
 		}
 
-		if configuration.SNMPVersion == "V3" {
+		if destination.Version == "V3" {
 			snmpArgument.Version = snmpgo.V3
-			snmpArgument.UserName = configuration.SNMPAuthenticationUsername
+			snmpArgument.UserName = destination.AuthenticationUsername
 
-			if configuration.SNMPAuthenticationEnabled && configuration.SNMPPrivateEnabled {
+			if destination.AuthenticationEnabled && destination.PrivateEnabled {
 				snmpArgument.SecurityLevel = snmpgo.AuthPriv
-			} else if configuration.SNMPAuthenticationEnabled {
+			} else if destination.AuthenticationEnabled {
 				snmpArgument.SecurityLevel = snmpgo.AuthNoPriv
 			} else {
 				snmpArgument.SecurityLevel = snmpgo.NoAuthNoPriv
 			}
 
-			if configuration.SNMPPrivateEnabled {
-				snmpArgument.PrivProtocol = snmpgo.PrivProtocol(configuration.SNMPPrivateProtocol)
-				snmpArgument.PrivPassword = configuration.SNMPPrivatePassword
+			if destination.PrivateEnabled {
+				snmpArgument.PrivProtocol = snmpgo.PrivProtocol(destination.PrivateProtocol)
+				snmpArgument.PrivPassword = destination.PrivatePassword
 			}
 
-			if configuration.SNMPAuthenticationEnabled {
-				snmpArgument.AuthProtocol = snmpgo.AuthProtocol(configuration.SNMPAuthenticationProtocol)
-				snmpArgument.AuthPassword = configuration.SNMPAuthenticationPassword
+			if destination.AuthenticationEnabled {
+				snmpArgument.AuthProtocol = snmpgo.AuthProtocol(destination.AuthenticationProtocol)
+				snmpArgument.AuthPassword = destination.AuthenticationPassword
 			}
 
-			snmpArgument.SecurityEngineId = configuration.SNMPSecurityEngineID
-			snmpArgument.ContextEngineId = configuration.SNMPContextEngineID
-			snmpArgument.ContextName = configuration.SNMPContextName
+			snmpArgument.SecurityEngineId = destination.SecurityEngineID
+			snmpArgument.ContextEngineId = destination.ContextEngineID
+			snmpArgument.ContextName = destination.ContextName
 		}
 
 		snmpArguments = append(snmpArguments, snmpArgument)
@@ -16,14 +16,20 @@ package trapsender
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"text/template"
 
+	"github.com/maxwo/snmp_notifier/telemetry"
 	"github.com/maxwo/snmp_notifier/types"
 
 	"log/slog"
@@ -31,6 +37,8 @@ import (
 	testutils "github.com/maxwo/snmp_notifier/test"
 
 	"github.com/k-sone/snmpgo"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 var dummyDescriptionTemplate = `{{ len .Alerts }}/{{ len .DeclaredAlerts }} alerts are firing:
@@ -44,6 +52,8 @@ var invalidDescriptionTemplate = `{{ range $key, $value := .InvalidAlerts }}{{ e
 
 var userObjectTemplate = `Alert count: {{ len .Alerts }}`
 
+var testHeartbeat = Heartbeat{TrapOID: "1.3.6.1.4.1.98789.5", Uptime: time.Minute, SentTraps: 3, FailedTraps: 1}
+
 func TestSimpleV2Trap(t *testing.T) {
 	port, server, channel, err := testutils.LaunchTrapReceiver()
 	if err != nil {
@@ -54,23 +64,27 @@ func TestSimpleV2Trap(t *testing.T) {
 	expectTraps(t, "test_mixed_bucket.json",
 		"test_mixed_traps.json",
 		Configuration{
-			SNMPDestination:            []string{fmt.Sprintf("127.0.0.1:%d", *port)},
-			SNMPRetries:                1,
-			SNMPVersion:                "V2c",
-			SNMPTimeout:                5 * time.Second,
-			SNMPCommunity:              "public",
-			SNMPAuthenticationEnabled:  false,
-			SNMPAuthenticationProtocol: "",
-			SNMPAuthenticationUsername: "",
-			SNMPAuthenticationPassword: "",
-			SNMPPrivateEnabled:         false,
-			SNMPPrivateProtocol:        "",
-			SNMPPrivatePassword:        "",
-			SNMPSecurityEngineID:       "",
-			SNMPContextEngineID:        "",
-			SNMPContextName:            "",
-			DescriptionTemplate:        *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
-			UserObjects:                make([]UserObject, 0),
+			SNMPDestinations: []Destination{
+				{
+					Address:                fmt.Sprintf("127.0.0.1:%d", *port),
+					Retries:                1,
+					Version:                "V2c",
+					Timeout:                5 * time.Second,
+					Community:              "public",
+					AuthenticationEnabled:  false,
+					AuthenticationProtocol: "",
+					AuthenticationUsername: "",
+					AuthenticationPassword: "",
+					PrivateEnabled:         false,
+					PrivateProtocol:        "",
+					PrivatePassword:        "",
+					SecurityEngineID:       "",
+					ContextEngineID:        "",
+					ContextName:            "",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
 		}, channel)
 }
 
@@ -84,22 +98,26 @@ func TestV2TrapWithUserObject(t *testing.T) {
 	expectTraps(t, "test_mixed_bucket_user_objects.json",
 		"test_mixed_traps_user_objects.json",
 		Configuration{
-			SNMPDestination:            []string{fmt.Sprintf("127.0.0.1:%d", *port)},
-			SNMPRetries:                1,
-			SNMPVersion:                "V2c",
-			SNMPTimeout:                5 * time.Second,
-			SNMPCommunity:              "public",
-			SNMPAuthenticationEnabled:  false,
-			SNMPAuthenticationProtocol: "",
-			SNMPAuthenticationUsername: "",
-			SNMPAuthenticationPassword: "",
-			SNMPPrivateEnabled:         false,
-			SNMPPrivateProtocol:        "",
-			SNMPPrivatePassword:        "",
-			SNMPSecurityEngineID:       "",
-			SNMPContextEngineID:        "",
-			SNMPContextName:            "",
-			DescriptionTemplate:        *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			SNMPDestinations: []Destination{
+				{
+					Address:                fmt.Sprintf("127.0.0.1:%d", *port),
+					Retries:                1,
+					Version:                "V2c",
+					Timeout:                5 * time.Second,
+					Community:              "public",
+					AuthenticationEnabled:  false,
+					AuthenticationProtocol: "",
+					AuthenticationUsername: "",
+					AuthenticationPassword: "",
+					PrivateEnabled:         false,
+					PrivateProtocol:        "",
+					PrivatePassword:        "",
+					SecurityEngineID:       "",
+					ContextEngineID:        "",
+					ContextName:            "",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
 			UserObjects: []UserObject{
 				{
 					SubOID:          8,
@@ -109,6 +127,115 @@ func TestV2TrapWithUserObject(t *testing.T) {
 		}, channel)
 }
 
+func TestV2TrapWithTypedUserObject(t *testing.T) {
+	port, server, channel, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	defer server.Close()
+
+	configuration := Configuration{
+		SNMPDestinations: []Destination{
+			{
+				Address:   fmt.Sprintf("127.0.0.1:%d", *port),
+				Retries:   1,
+				Version:   "V2c",
+				Timeout:   5 * time.Second,
+				Community: "public",
+			},
+		},
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+		UserObjects: []UserObject{
+			{
+				SubOID:          8,
+				Type:            Gauge32ObjectType,
+				ContentTemplate: *template.Must(template.New("alertCountTemplate").Parse(`{{ len .Alerts }}`)),
+			},
+		},
+	}
+	if !sendTraps(t, "test_mixed_bucket_user_objects.json", configuration) {
+		return
+	}
+
+	receivedTraps := testutils.ReadTraps(channel)
+	for _, expectedTrap := range readTrapsFile(t, "test_mixed_traps_typed_user_objects.json") {
+		if !testutils.FindTrap(receivedTraps, expectedTrap) {
+			t.Fatal("Expected trap not found:", expectedTrap)
+		}
+	}
+	for _, trap := range receivedTraps {
+		varBind := trap.Pdu.VarBinds().MatchOid(snmpgo.MustNewOid("1.2.3.7.8"))
+		if varBind == nil || varBind.Variable.Type() != "Gauge32" {
+			t.Error("user object should be a Gauge32:", varBind)
+		}
+	}
+}
+
+func TestV2TrapWithUnconvertibleUserObject(t *testing.T) {
+	expectErrorOnSending(t,
+		"test_mixed_bucket_user_objects.json",
+		Configuration{
+			SNMPDestinations: []Destination{
+				{
+					Address:   "127.0.0.1:162",
+					Retries:   1,
+					Version:   "V2c",
+					Timeout:   5 * time.Second,
+					Community: "public",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects: []UserObject{
+				{
+					SubOID:          8,
+					Type:            IntegerObjectType,
+					ContentTemplate: *template.Must(template.New("userObjectTemplate").Parse(userObjectTemplate)),
+				},
+			},
+		})
+}
+
+func TestObjectTypeConversions(t *testing.T) {
+	tests := []struct {
+		objectType string
+		value      string
+		expected   snmpgo.Variable
+	}{
+		{StringObjectType, " text ", snmpgo.NewOctetString([]byte("text"))},
+		{"", "text", snmpgo.NewOctetString([]byte("text"))},
+		{IntegerObjectType, "-42", snmpgo.NewInteger(-42)},
+		{Counter32ObjectType, "42", snmpgo.NewCounter32(42)},
+		{Gauge32ObjectType, "42\n", snmpgo.NewGauge32(42)},
+		{Counter64ObjectType, "18446744073709551615", snmpgo.NewCounter64(18446744073709551615)},
+		{TimeTicksObjectType, "360000", snmpgo.NewTimeTicks(360000)},
+		{IPAddressObjectType, "10.0.0.1", snmpgo.NewIpaddress(10, 0, 0, 1)},
+		{OIDObjectType, ".1.3.6.1.4.1.98789", snmpgo.MustNewOid("1.3.6.1.4.1.98789")},
+	}
+	for _, test := range tests {
+		variable, err := newVariable(test.objectType, test.value)
+		if err != nil {
+			t.Error("unexpected error converting", test.value, "to", test.objectType, err)
+			continue
+		}
+		if variable.Type() != test.expected.Type() || variable.String() != test.expected.String() {
+			t.Error("unexpected conversion of", test.value, "to", test.objectType, variable)
+		}
+	}
+
+	for _, test := range []struct{ objectType, value string }{
+		{IntegerObjectType, "2147483648"},
+		{Counter32ObjectType, "-1"},
+		{Gauge32ObjectType, "many"},
+		{IPAddressObjectType, "::1"},
+		{OIDObjectType, "not.an.oid"},
+		{"float", "1.5"},
+	} {
+		if _, err := newVariable(test.objectType, test.value); err == nil {
+			t.Error("converting", test.value, "to", test.objectType, "should fail")
+		}
+	}
+}
+
 func TestV2TrapWithCustomOID(t *testing.T) {
 	port, server, channel, err := testutils.LaunchTrapReceiver()
 	if err != nil {
@@ -120,23 +247,27 @@ func TestV2TrapWithCustomOID(t *testing.T) {
 		"test_mixed_bucket.json",
 		"test_mixed_traps_custom_base_oid.json",
 		Configuration{
-			SNMPDestination:            []string{fmt.Sprintf("127.0.0.1:%d", *port)},
-			SNMPRetries:                1,
-			SNMPVersion:                "V2c",
-			SNMPTimeout:                5 * time.Second,
-			SNMPCommunity:              "public",
-			SNMPAuthenticationEnabled:  false,
-			SNMPAuthenticationProtocol: "",
-			SNMPAuthenticationUsername: "",
-			SNMPAuthenticationPassword: "",
-			SNMPPrivateEnabled:         false,
-			SNMPPrivateProtocol:        "",
-			SNMPPrivatePassword:        "",
-			SNMPSecurityEngineID:       "",
-			SNMPContextEngineID:        "",
-			SNMPContextName:            "",
-			DescriptionTemplate:        *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
-			UserObjects:                make([]UserObject, 0),
+			SNMPDestinations: []Destination{
+				{
+					Address:                fmt.Sprintf("127.0.0.1:%d", *port),
+					Retries:                1,
+					Version:                "V2c",
+					Timeout:                5 * time.Second,
+					Community:              "public",
+					AuthenticationEnabled:  false,
+					AuthenticationProtocol: "",
+					AuthenticationUsername: "",
+					AuthenticationPassword: "",
+					PrivateEnabled:         false,
+					PrivateProtocol:        "",
+					PrivatePassword:        "",
+					SecurityEngineID:       "",
+					ContextEngineID:        "",
+					ContextName:            "",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
 		}, channel)
 }
 
@@ -151,29 +282,83 @@ func TestSimpleV3Trap(t *testing.T) {
 		"test_mixed_bucket.json",
 		"test_mixed_traps.json",
 		Configuration{
-			SNMPDestination:            []string{fmt.Sprintf("127.0.0.1:%d", *port)},
-			SNMPRetries:                1,
-			SNMPVersion:                "V3",
-			SNMPTimeout:                5 * time.Second,
-			SNMPCommunity:              "",
-			SNMPAuthenticationEnabled:  true,
-			SNMPAuthenticationProtocol: "SHA",
-			SNMPAuthenticationUsername: "v3_username",
-			SNMPAuthenticationPassword: "v3_password",
-			SNMPPrivateEnabled:         true,
-			SNMPPrivateProtocol:        "AES",
-			SNMPPrivatePassword:        "v3_private_secret",
-			SNMPSecurityEngineID:       "8000000004736e6d70676f",
-			SNMPContextEngineID:        "",
-			SNMPContextName:            "",
-			DescriptionTemplate:        *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
-			UserObjects:                make([]UserObject, 0),
+			SNMPDestinations: []Destination{
+				{
+					Address:                fmt.Sprintf("127.0.0.1:%d", *port),
+					Retries:                1,
+					Version:                "V3",
+					Timeout:                5 * time.Second,
+					Community:              "",
+					AuthenticationEnabled:  true,
+					AuthenticationProtocol: "SHA",
+					AuthenticationUsername: "v3_username",
+					AuthenticationPassword: "v3_password",
+					PrivateEnabled:         true,
+					PrivateProtocol:        "AES",
+					PrivatePassword:        "v3_private_secret",
+					SecurityEngineID:       "8000000004736e6d70676f",
+					ContextEngineID:        "",
+					ContextName:            "",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
 		},
 		channel)
 }
 
-func TestV2TrapWithInvalidDescriptionTemplate(t *testing.T) {
-	port, server, _, err := testutils.LaunchTrapReceiver()
+func TestV3TrapsWithStrongProtocols(t *testing.T) {
+	tests := []struct {
+		authenticationProtocol string
+		privateProtocol        string
+		inform                 bool
+	}{
+		{"SHA224", "AES192", false},
+		{"SHA256", "AES256", false},
+		{"SHA384", "AES192C", false},
+		{"SHA512", "AES256C", false},
+		{"SHA", "AES256", false},
+		{"SHA256", "AES", false},
+		{"SHA512", "AES256", true},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%s/inform=%t", test.authenticationProtocol, test.privateProtocol, test.inform), func(t *testing.T) {
+			port, server, channel, err := testutils.LaunchGoSNMPTrapReceiver(goSNMPAuthenticationProtocols[test.authenticationProtocol], goSNMPPrivateProtocols[test.privateProtocol])
+			if err != nil {
+				t.Fatal("Error while opening server:", err)
+			}
+			defer server.Close()
+
+			expectTraps(t, "test_mixed_bucket.json",
+				"test_mixed_traps.json",
+				Configuration{
+					SNMPDestinations: []Destination{
+						{
+							Address:                fmt.Sprintf("127.0.0.1:%d", *port),
+							Retries:                1,
+							Version:                "V3",
+							Timeout:                5 * time.Second,
+							Inform:                 test.inform,
+							AuthenticationEnabled:  true,
+							AuthenticationProtocol: test.authenticationProtocol,
+							AuthenticationUsername: "v3_username",
+							AuthenticationPassword: "v3_password",
+							PrivateEnabled:         true,
+							PrivateProtocol:        test.privateProtocol,
+							PrivatePassword:        "v3_private_secret",
+							SecurityEngineID:       "8000000004736e6d70676f",
+						},
+					},
+					DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+					UserObjects:         make([]UserObject, 0),
+				}, channel)
+		})
+	}
+}
+
+func TestV3TrapWithStrongProtocolsAuthenticationError(t *testing.T) {
+	port, server, _, err := testutils.LaunchGoSNMPTrapReceiver(goSNMPAuthenticationProtocols["SHA256"], goSNMPPrivateProtocols["AES256"])
 	if err != nil {
 		t.Fatal("Error while opening server:", err)
 	}
@@ -182,120 +367,1107 @@ func TestV2TrapWithInvalidDescriptionTemplate(t *testing.T) {
 	expectErrorOnSending(t,
 		"test_mixed_bucket.json",
 		Configuration{
-			SNMPDestination:            []string{fmt.Sprintf("127.0.0.1:%d", *port)},
-			SNMPRetries:                1,
-			SNMPVersion:                "V2c",
-			SNMPTimeout:                5 * time.Second,
-			SNMPCommunity:              "public",
-			SNMPAuthenticationEnabled:  false,
-			SNMPAuthenticationProtocol: "",
-			SNMPAuthenticationUsername: "",
-			SNMPAuthenticationPassword: "",
-			SNMPPrivateEnabled:         false,
-			SNMPPrivateProtocol:        "",
-			SNMPPrivatePassword:        "",
-			SNMPSecurityEngineID:       "",
-			SNMPContextEngineID:        "",
-			SNMPContextName:            "",
-			DescriptionTemplate:        *template.Must(template.New("invalidDescriptionTemplate").Parse(invalidDescriptionTemplate)),
-			UserObjects:                make([]UserObject, 0),
+			SNMPDestinations: []Destination{
+				{
+					Address:                fmt.Sprintf("127.0.0.1:%d", *port),
+					Retries:                0,
+					Version:                "V3",
+					Timeout:                500 * time.Millisecond,
+					Inform:                 true,
+					AuthenticationEnabled:  true,
+					AuthenticationProtocol: "SHA256",
+					AuthenticationUsername: "v3_username",
+					AuthenticationPassword: "wrong_password",
+					PrivateEnabled:         true,
+					PrivateProtocol:        "AES256",
+					PrivatePassword:        "v3_private_secret",
+					SecurityEngineID:       "8000000004736e6d70676f",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
 		})
 }
 
-func TestV3TrapWithAuthenticationError(t *testing.T) {
-	port, server, _, err := testutils.LaunchTrapReceiver()
+func TestTrapsWithDestinationsOfDifferentVersions(t *testing.T) {
+	v2Port, v2Server, v2Channel, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	defer v2Server.Close()
+
+	v3Port, v3Server, v3Channel, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	defer v3Server.Close()
+
+	expectTraps(t,
+		"test_mixed_bucket.json",
+		"test_mixed_traps.json",
+		Configuration{
+			SNMPDestinations: []Destination{
+				{
+					Address:   fmt.Sprintf("127.0.0.1:%d", *v2Port),
+					Retries:   1,
+					Version:   "V2c",
+					Timeout:   5 * time.Second,
+					Community: "public",
+				},
+				{
+					Address:                fmt.Sprintf("127.0.0.1:%d", *v3Port),
+					Retries:                1,
+					Version:                "V3",
+					Timeout:                5 * time.Second,
+					AuthenticationEnabled:  true,
+					AuthenticationProtocol: "SHA",
+					AuthenticationUsername: "v3_username",
+					AuthenticationPassword: "v3_password",
+					PrivateEnabled:         true,
+					PrivateProtocol:        "AES",
+					PrivatePassword:        "v3_private_secret",
+					SecurityEngineID:       "8000000004736e6d70676f",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
+		},
+		v2Channel, v3Channel)
+}
+
+func TestRoutedTraps(t *testing.T) {
+	networkPort, networkServer, networkChannel, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	defer networkServer.Close()
+
+	datacenterPort, datacenterServer, datacenterChannel, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	defer datacenterServer.Close()
+
+	networkMatcher, _ := labels.NewMatcher(labels.MatchEqual, "team", "network")
+
+	sendTraps(t,
+		"test_routed_bucket.json",
+		Configuration{
+			SNMPDestinations: []Destination{
+				{
+					Name:      "network",
+					Address:   fmt.Sprintf("127.0.0.1:%d", *networkPort),
+					Retries:   1,
+					Version:   "V2c",
+					Timeout:   5 * time.Second,
+					Community: "public",
+				},
+				{
+					Name:      "datacenter",
+					Address:   fmt.Sprintf("127.0.0.1:%d", *datacenterPort),
+					Retries:   1,
+					Version:   "V2c",
+					Timeout:   5 * time.Second,
+					Community: "public",
+				},
+			},
+			Routes: []Route{
+				{
+					Matchers:     labels.Matchers{networkMatcher},
+					Destinations: []string{"network"},
+				},
+				{
+					Receiver:     "other-receiver",
+					Destinations: []string{"network"},
+				},
+			},
+			DefaultDestinations: []string{"datacenter"},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
+		})
+
+	expectReceivedTraps(t, "test_routed_network_traps.json", networkChannel)
+	expectReceivedTraps(t, "test_routed_datacenter_traps.json", datacenterChannel)
+}
+
+func TestRenderRoutedTraps(t *testing.T) {
+	networkMatcher, _ := labels.NewMatcher(labels.MatchEqual, "team", "network")
+
+	trapSender := New(Configuration{
+		SNMPDestinations: []Destination{
+			{Name: "network", Address: "127.0.0.1:162", Version: "V2c", Community: "public"},
+			{Name: "datacenter", Address: "127.0.0.2:162", Version: "V2c", Community: "public"},
+		},
+		Routes: []Route{
+			{
+				Matchers:     labels.Matchers{networkMatcher},
+				Destinations: []string{"network"},
+			},
+		},
+		DefaultDestinations: []string{"datacenter"},
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+		UserObjects:         make([]UserObject, 0),
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	traps, err := trapSender.RenderTraps(readBucketFile(t, "test_routed_bucket.json"))
+	if err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+	if len(traps) != 2 {
+		t.Fatal("2 traps expected, got", traps)
+	}
+
+	for _, destination := range []string{"network", "datacenter"} {
+		expectedTraps := readTrapsFile(t, fmt.Sprintf("test_routed_%s_traps.json", destination))
+		for _, expectedTrap := range expectedTraps {
+			if !findRenderedTrap(traps, destination, expectedTrap) {
+				t.Error("Expected trap not rendered for", destination, ":", expectedTrap)
+			}
+		}
+	}
+
+	if text := traps[0].String(); !strings.HasPrefix(text, "Trap "+traps[0].TrapOID+"\n") || !strings.Contains(text, "  Destinations: ") {
+		t.Error("unexpected text form of the trap:", text)
+	}
+}
+
+func TestDryRunTraps(t *testing.T) {
+	dryRunFile := filepath.Join(t.TempDir(), "traps.jsonl")
+	address := "127.0.0.1:65162"
+	dryRunTraps := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "dry_run"))
+
+	configuration := Configuration{
+		SNMPDestinations: []Destination{
+			{Name: "dry-run", Address: address, Retries: 1, Version: "V2c", Timeout: 5 * time.Second, Community: "public"},
+		},
+		SpoolDirectory:      t.TempDir(),
+		DryRun:              true,
+		DryRunFile:          dryRunFile,
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+		UserObjects:         make([]UserObject, 0),
+	}
+	if !sendTraps(t, "test_mixed_bucket.json", configuration) {
+		return
+	}
+
+	if traps := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "dry_run")) - dryRunTraps; traps != 2 {
+		t.Error("2 traps should be counted as a dry run, got", traps)
+	}
+
+	content, err := os.ReadFile(dryRunFile)
+	if err != nil {
+		t.Fatal("Error while reading dry run file:", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	records := []RenderedTrap{}
+	for _, line := range lines {
+		record := RenderedTrap{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal("Error while parsing dry run file:", err)
+		}
+		records = append(records, record)
+	}
+	for _, expectedTrap := range readTrapsFile(t, "test_mixed_traps.json") {
+		if !findRenderedTrap(records, "dry-run", expectedTrap) {
+			t.Error("Expected trap not written to the dry run file:", expectedTrap)
+		}
+	}
+}
+
+// the group of test_mixed_bucket.json with 2 firing alerts
+const criticalGroupID = "1.2.3.2.1[environment=production,label=test]"
+
+func TestTruncatedDescription(t *testing.T) {
+	var tests = []struct {
+		maxVarBindSize int
+		description    string
+	}{
+		{
+			170,
+			"1/3 alerts are firing:\nAlert name: TestAlert\nSeverity: warning\nSummary: this is the random summary\nDescription: this is the description of alert 1... (1 more alert)",
+		},
+		{
+			100,
+			"1/3 alerts are firing:\nAlert name: TestAlert\nSeverity: warning\nSummary: this is th... (1 more alert)",
+		},
+		{
+			10,
+			"... (1 mor",
+		},
+	}
+
+	for _, test := range tests {
+		truncatedTraps := testutil.ToFloat64(telemetry.SNMPTruncatedTrapsTotal)
+		traps := renderSizeLimitedTraps(t, Configuration{MaxVarBindSize: test.maxVarBindSize})
+		if description := findRenderedVarBind(traps, criticalGroupID, "1.2.3.2.2.3"); description != test.description {
+			t.Errorf("the description truncated to %d bytes should be %q, got %q", test.maxVarBindSize, test.description, description)
+		}
+		if truncated := testutil.ToFloat64(telemetry.SNMPTruncatedTrapsTotal) - truncatedTraps; truncated < 1 {
+			t.Error("the truncated traps should be counted, got", truncated)
+		}
+	}
+}
+
+func TestTruncatedUserObject(t *testing.T) {
+	traps := renderSizeLimitedTraps(t, Configuration{
+		MaxVarBindSize: 40,
+		UserObjects: []UserObject{
+			{SubOID: 10, ContentTemplate: *template.Must(template.New("summary").Parse(`{{ range .Alerts }}{{ .Annotations.summary }}, {{ end }}`))},
+			{SubOID: 11, Type: IntegerObjectType, ContentTemplate: *template.Must(template.New("count").Parse(`{{ len .Alerts }}`))},
+		},
+	})
+	if userObject := findRenderedVarBind(traps, criticalGroupID, "1.2.3.2.2.10"); userObject != "this is the random summary, this is t..." {
+		t.Error("unexpected truncated user object", userObject)
+	}
+	if userObject := findRenderedVarBind(traps, criticalGroupID, "1.2.3.2.2.11"); userObject != "2" {
+		t.Error("non-string user objects should not be truncated, got", userObject)
+	}
+}
+
+func TestMaxPDUSize(t *testing.T) {
+	trapSender := New(Configuration{
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	alertBucket := readBucketFile(t, "test_mixed_bucket.json")
+	varBinds, err := trapSender.generateVarBinds(criticalGroupID, *alertBucket.AlertGroups[criticalGroupID])
+	if err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+	untruncatedSize, _ := pduSize(varBinds)
+
+	maxPDUSize := untruncatedSize - 100
+	trapSender = New(Configuration{
+		MaxPDUSize:          maxPDUSize,
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	varBinds, err = trapSender.generateVarBinds(criticalGroupID, *alertBucket.AlertGroups[criticalGroupID])
+	if err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+	if size, _ := pduSize(varBinds); size > maxPDUSize {
+		t.Errorf("the PDU should be truncated to %d bytes, got %d", maxPDUSize, size)
+	}
+	if description := varBinds[4].Variable.String(); !strings.HasSuffix(description, "... (1 more alert)") {
+		t.Error("the description should be truncated, got", description)
+	}
+
+	trapSender = New(Configuration{
+		MaxPDUSize:          100,
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	if _, err := trapSender.generateVarBinds(criticalGroupID, *alertBucket.AlertGroups[criticalGroupID]); err == nil {
+		t.Error("a trap exceeding the maximum PDU size without its description should fail")
+	}
+}
+
+func TestSplitAlertGroups(t *testing.T) {
+	trapSender := New(Configuration{
+		SNMPDestinations:    []Destination{{Name: "destination", Address: "127.0.0.1:162", Version: "V2c", Community: "public"}},
+		MaxAlertsPerTrap:    2,
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	traps, err := trapSender.RenderTraps(readBucketFile(t, "test_split_bucket.json"))
+	if err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+	if len(traps) != 2 {
+		t.Fatal("2 traps expected, got", traps)
+	}
+
+	expectedParts := []map[string]string{
+		{
+			"1.2.3.2.2.1": "1.2.3.2.1[environment=production]",
+			"1.2.3.2.2.3": "2/3 alerts are firing:\nAlert name: HighLoad\nSeverity: warning\nSummary: summary of HighLoad\nDescription: description of HighLoad\nAlert name: NodeDown\nSeverity: critical\nSummary: summary of NodeDown\nDescription: description of NodeDown",
+			"1.2.3.2.2.6": "1",
+			"1.2.3.2.2.7": "2",
+		},
+		{
+			"1.2.3.2.2.1": "1.2.3.2.1[environment=production]",
+			"1.2.3.2.2.3": "1/3 alerts are firing:\nAlert name: DiskFull\nSeverity: critical\nSummary: summary of DiskFull\nDescription: description of DiskFull",
+			"1.2.3.2.2.6": "2",
+			"1.2.3.2.2.7": "2",
+		},
+	}
+	for _, expectedPart := range expectedParts {
+		if !findRenderedTrap(traps, "destination", expectedPart) {
+			t.Error("Expected part not rendered:", expectedPart)
+		}
+	}
+
+	trapSender = New(Configuration{
+		SNMPDestinations:    []Destination{{Name: "destination", Address: "127.0.0.1:162", Version: "V2c", Community: "public"}},
+		MaxAlertsPerTrap:    5,
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	traps, err = trapSender.RenderTraps(readBucketFile(t, "test_split_bucket.json"))
+	if err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+	isPartVarBind := func(varBind RenderedVarBind) bool {
+		return varBind.OID == "1.2.3.2.2.6" || varBind.OID == "1.2.3.2.2.7"
+	}
+	if len(traps) != 1 || slices.ContainsFunc(traps[0].VarBinds, isPartVarBind) {
+		t.Error("the groups that are not split should be sent without part index and count, got", traps)
+	}
+}
+
+func TestSimpleV1Trap(t *testing.T) {
+	port, server, channel, err := testutils.LaunchV1TrapReceiver()
 	if err != nil {
 		t.Fatal("Error while opening server:", err)
 	}
 	defer server.Close()
 
+	expectTraps(t, "test_mixed_bucket.json",
+		"test_v1_traps.json",
+		Configuration{
+			SNMPDestinations: []Destination{
+				{
+					Address:      fmt.Sprintf("127.0.0.1:%d", *port),
+					Retries:      1,
+					Version:      "V1",
+					Timeout:      5 * time.Second,
+					Community:    "public",
+					AgentAddress: "10.0.0.1",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
+		}, channel)
+}
+
+func TestV1TrapConversion(t *testing.T) {
+	varBinds := func(trapOID string) snmpgo.VarBinds {
+		return snmpgo.VarBinds{
+			snmpgo.NewVarBind(snmpgo.OidSysUpTime, snmpgo.NewTimeTicks(1234)),
+			snmpgo.NewVarBind(snmpgo.OidSnmpTrap, snmpgo.MustNewOid(trapOID)),
+			snmpgo.NewVarBind(snmpgo.MustNewOid("1.2.3.4"), snmpgo.NewOctetString([]byte("value"))),
+			snmpgo.NewVarBind(snmpgo.MustNewOid("1.2.3.5"), snmpgo.NewCounter64(1)),
+		}
+	}
+
+	tests := []struct {
+		trapOID      string
+		enterprise   string
+		genericTrap  int
+		specificTrap int
+	}{
+		{"1.3.6.1.6.3.1.1.5.3", "1.3.6.1.6.3.1.1.5", 2, 0},
+		{"1.3.6.1.4.1.98789.0.5", "1.3.6.1.4.1.98789", 6, 5},
+		{"1.3.6.1.4.1.98789.1", "1.3.6.1.4.1.98789", 6, 1},
+	}
+
+	for _, test := range tests {
+		trap := newV1Trap(varBinds(test.trapOID), net.ParseIP("10.0.0.1"))
+		if trap.enterprise.String() != test.enterprise || trap.genericTrap != test.genericTrap || trap.specificTrap != test.specificTrap {
+			t.Errorf("unexpected conversion of %s: enterprise %s, generic trap %d, specific trap %d", test.trapOID, trap.enterprise, trap.genericTrap, trap.specificTrap)
+		}
+		if trap.timeStamp != 1234 {
+			t.Errorf("unexpected time stamp for %s: %d", test.trapOID, trap.timeStamp)
+		}
+		if len(trap.varBinds) != 1 {
+			t.Errorf("unexpected variables for %s: %s", test.trapOID, trap.varBinds)
+		}
+	}
+}
+
+func TestSimpleV2Inform(t *testing.T) {
+	port, server, channel, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	defer server.Close()
+
+	expectTraps(t, "test_mixed_bucket.json",
+		"test_mixed_traps.json",
+		Configuration{
+			SNMPDestinations: []Destination{
+				{
+					Address:   fmt.Sprintf("127.0.0.1:%d", *port),
+					Retries:   1,
+					Version:   "V2c",
+					Timeout:   5 * time.Second,
+					Inform:    true,
+					Community: "public",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
+		}, channel)
+}
+
+func TestUnacknowledgedInforms(t *testing.T) {
+	port, server, _, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	server.Close()
+
+	address := fmt.Sprintf("127.0.0.1:%d", *port)
+	successes := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "success"))
+	failures := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure"))
+
 	expectErrorOnSending(t,
 		"test_mixed_bucket.json",
 		Configuration{
-			SNMPDestination:            []string{fmt.Sprintf("127.0.0.1:%d", *port)},
-			SNMPRetries:                1,
-			SNMPVersion:                "V3",
-			SNMPTimeout:                5 * time.Second,
-			SNMPCommunity:              "",
-			SNMPAuthenticationEnabled:  true,
-			SNMPAuthenticationProtocol: "SHA",
-			SNMPAuthenticationUsername: "v3_username",
-			SNMPAuthenticationPassword: "v3_password",
-			SNMPPrivateEnabled:         true,
-			SNMPPrivateProtocol:        "AES",
-			SNMPPrivatePassword:        "v3_private_secret",
-			SNMPSecurityEngineID:       "",
-			SNMPContextEngineID:        "",
-			SNMPContextName:            "",
-			DescriptionTemplate:        *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
-			UserObjects:                make([]UserObject, 0),
+			SNMPDestinations: []Destination{
+				{
+					Address:   address,
+					Retries:   1,
+					Version:   "V2c",
+					Timeout:   100 * time.Millisecond,
+					Inform:    true,
+					Community: "public",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
 		})
+
+	if testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "success")) != successes {
+		t.Error("unacknowledged informs should not be counted as successes")
+	}
+	if testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure")) == failures {
+		t.Error("unacknowledged informs should be counted as failures")
+	}
 }
 
-func expectErrorOnSending(t *testing.T, bucketFileName string, configuration Configuration) {
-	bucketByteData, err := os.ReadFile(bucketFileName)
+func TestConcurrentDestinationsWithSendTimeout(t *testing.T) {
+	port, server, channel, err := testutils.LaunchTrapReceiver()
 	if err != nil {
-		t.Fatal("Error while reading bucket file:", err)
+		t.Fatal("Error while opening server:", err)
 	}
-	bucketReader := bytes.NewReader(bucketByteData)
-	bucketData := types.AlertBucket{}
-	err = json.NewDecoder(bucketReader).Decode(&bucketData)
+	defer server.Close()
+
+	// a destination never acknowledging informs
+	blackhole, err := net.ListenPacket("udp", "127.0.0.1:0")
 	if err != nil {
-		t.Fatal("Error while parsing bucket file:", err)
+		t.Fatal("Error while opening server:", err)
+	}
+	defer blackhole.Close()
+
+	trapSender := New(Configuration{
+		SNMPDestinations: []Destination{
+			{
+				Name:      "blackhole",
+				Address:   blackhole.LocalAddr().String(),
+				Retries:   0,
+				Version:   "V2c",
+				Timeout:   5 * time.Second,
+				Inform:    true,
+				Community: "public",
+			},
+			{
+				Name:      "manager",
+				Address:   fmt.Sprintf("127.0.0.1:%d", *port),
+				Retries:   1,
+				Version:   "V2c",
+				Timeout:   5 * time.Second,
+				Community: "public",
+			},
+		},
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+		UserObjects:         make([]UserObject, 0),
+		SendTimeout:         500 * time.Millisecond,
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	start := time.Now()
+	err = trapSender.SendAlertTraps(readBucketFile(t, "test_mixed_bucket.json"))
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Error("sending should stop after the send timeout, took", elapsed)
+	}
+	if !errors.Is(err, errSendTimeout) || !strings.Contains(err.Error(), "destination blackhole:") || strings.Contains(err.Error(), "manager") {
+		t.Error("only the blackhole destination should be reported as timed out, got", err)
+	}
+
+	expectReceivedTraps(t, "test_mixed_traps.json", channel)
+}
+
+func TestQueuedTraps(t *testing.T) {
+	port, server, channel, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	defer server.Close()
+
+	expectTraps(t, "test_mixed_bucket.json",
+		"test_mixed_traps.json",
+		Configuration{
+			SNMPDestinations: []Destination{
+				{
+					Address:   fmt.Sprintf("127.0.0.1:%d", *port),
+					Retries:   1,
+					Version:   "V2c",
+					Timeout:   5 * time.Second,
+					Community: "public",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
+			QueueSize:           10,
+			QueueWorkers:        2,
+			QueueRetries:        1,
+			QueueRetryBackoff:   time.Second,
+		}, channel)
+}
+
+func TestQueuedTrapsRetried(t *testing.T) {
+	port, server, _, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	server.Close()
+
+	address := fmt.Sprintf("127.0.0.1:%d", *port)
+	failures := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure"))
+	retries := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "retry"))
+
+	trapSender := New(Configuration{
+		SNMPDestinations: []Destination{
+			{
+				Address:   address,
+				Retries:   0,
+				Version:   "V2c",
+				Timeout:   100 * time.Millisecond,
+				Inform:    true,
+				Community: "public",
+			},
+		},
+		DescriptionTemplate:  *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+		UserObjects:          make([]UserObject, 0),
+		QueueSize:            10,
+		QueueWorkers:         1,
+		QueueRetries:         2,
+		QueueRetryBackoff:    10 * time.Millisecond,
+		QueueMaxRetryBackoff: 15 * time.Millisecond,
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	defer trapSender.Stop()
+
+	if err := trapSender.SendAlertTraps(readBucketFile(t, "test_mixed_bucket.json")); err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+
+	// 2 traps, sent 3 times each, and counted as failed once
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure"))-failures < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("queued traps were not retried")
+		}
+		time.Sleep(50 * time.Millisecond)
 	}
+	if count := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "retry")) - retries; count != 4 {
+		t.Error("4 retried attempts expected, got", count)
+	}
+}
+
+func TestQueuedRetriesInterruptedByStop(t *testing.T) {
+	port, server, _, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	server.Close()
 
+	address := fmt.Sprintf("127.0.0.1:%d", *port)
+	configuration := queuedInformConfiguration(address)
+	configuration.QueueSize = 10
+	configuration.QueueRetries = 5
+	configuration.QueueRetryBackoff = time.Hour
+	configuration.QueueMaxRetryBackoff = time.Hour
 	trapSender := New(configuration, slog.New(slog.NewTextHandler(os.Stdout, nil)))
 
-	err = trapSender.SendAlertTraps(bucketData)
-	if err == nil {
-		t.Error("An error was expected")
+	failures := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure"))
+	retries := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "retry"))
+
+	if err := trapSender.SendAlertTraps(readBucketFile(t, "test_mixed_bucket.json")); err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "retry")) == retries {
+		if time.Now().After(deadline) {
+			t.Fatal("the first queued trap should fail")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the worker waiting for an hour tries the traps a last time
+	trapSender.Stop()
+	deadline = time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure"))-failures < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("the retries of the queued traps should be given up once stopped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if count := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "retry")) - retries; count != 1 {
+		t.Error("1 retried attempt expected, got", count)
 	}
 }
 
-func expectTraps(t *testing.T, bucketFileName string, trapFileName string, configuration Configuration, channel chan *snmpgo.TrapRequest) {
+func TestFullAndClosedDeliveryQueue(t *testing.T) {
+	queue := &deliveryQueue{traps: make(chan queuedTrap, 1), done: make(chan struct{})}
 
-	bucketByteData, err := os.ReadFile(bucketFileName)
+	if !queue.enqueue(snmpgo.VarBinds{}, true) {
+		t.Error("trap should be queued")
+	}
+	if queue.enqueue(snmpgo.VarBinds{}, true) {
+		t.Error("trap should not be queued in a full queue")
+	}
+
+	queue.close()
+	<-queue.traps
+	if queue.enqueue(snmpgo.VarBinds{}, true) {
+		t.Error("trap should not be queued in a closed queue")
+	}
+}
+
+func TestSpooledTrapsReplayed(t *testing.T) {
+	port, server, _, err := testutils.LaunchTrapReceiver()
 	if err != nil {
-		t.Fatal("Error while reading bucket file:", err)
+		t.Fatal("Error while opening server:", err)
 	}
-	bucketReader := bytes.NewReader(bucketByteData)
-	bucketData := types.AlertBucket{}
-	err = json.NewDecoder(bucketReader).Decode(&bucketData)
+	server.Close()
+
+	spoolDirectory := t.TempDir()
+	unreachableAddress := fmt.Sprintf("127.0.0.1:%d", *port)
+
+	unreachableTrapSender := New(Configuration{
+		SNMPDestinations: []Destination{
+			{
+				Name:      "manager",
+				Address:   unreachableAddress,
+				Retries:   0,
+				Version:   "V2c",
+				Timeout:   100 * time.Millisecond,
+				Inform:    true,
+				Community: "public",
+			},
+		},
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+		UserObjects:         make([]UserObject, 0),
+		SpoolDirectory:      spoolDirectory,
+		SpoolReplayInterval: time.Hour,
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	if err := unreachableTrapSender.SendAlertTraps(readBucketFile(t, "test_mixed_bucket.json")); err != nil {
+		t.Fatal("spooled traps should not be reported as errors:", err)
+	}
+	unreachableTrapSender.Stop()
+
+	if length := testutil.ToFloat64(telemetry.SNMPSpoolLength.WithLabelValues(unreachableAddress)); length != 2 {
+		t.Fatal("2 traps should be spooled, got", length)
+	}
+
+	port, server, channel, err := testutils.LaunchTrapReceiver()
 	if err != nil {
-		t.Fatal("Error while parsing bucket file:", err)
+		t.Fatal("Error while opening server:", err)
 	}
+	defer server.Close()
+
+	trapSender := New(Configuration{
+		SNMPDestinations: []Destination{
+			{
+				Name:      "manager",
+				Address:   fmt.Sprintf("127.0.0.1:%d", *port),
+				Retries:   1,
+				Version:   "V2c",
+				Timeout:   5 * time.Second,
+				Community: "public",
+			},
+		},
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+		UserObjects:         make([]UserObject, 0),
+		SpoolDirectory:      spoolDirectory,
+		SpoolReplayInterval: 50 * time.Millisecond,
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	defer trapSender.Stop()
 
+	expectReceivedTraps(t, "test_mixed_traps.json", channel)
+
+	files, err := listSpooledTraps(trapSender.spoolDirectory(0))
+	if err != nil {
+		t.Fatal("Error while reading spool:", err)
+	}
+	if len(files) != 0 {
+		t.Error("replayed traps should be removed from the spool, got", files)
+	}
+}
+
+func TestSpoolLimits(t *testing.T) {
+	address := "127.0.0.1:1"
+	configuration := Configuration{
+		SNMPDestinations: []Destination{
+			{Name: "limited", Address: address, Version: "V2c", Community: "public"},
+		},
+		SpoolDirectory:      t.TempDir(),
+		SpoolMaxSize:        1,
+		SpoolReplayInterval: time.Hour,
+	}
 	trapSender := New(configuration, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	defer trapSender.Stop()
+
+	maxSizeDrops := testutil.ToFloat64(telemetry.SNMPSpoolDroppedTotal.WithLabelValues(address, "max_size"))
+	maxAgeDrops := testutil.ToFloat64(telemetry.SNMPSpoolDroppedTotal.WithLabelValues(address, "max_age"))
 
-	err = trapSender.SendAlertTraps(bucketData)
+	trap := snmpgo.VarBinds{snmpgo.NewVarBind(snmpgo.OidSnmpTrap, snmpgo.MustNewOid("1.3.6.1.4.1.98789.1"))}
+	if err := trapSender.spoolTraps(0, []snmpgo.VarBinds{trap, trap}); err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+	if drops := testutil.ToFloat64(telemetry.SNMPSpoolDroppedTotal.WithLabelValues(address, "max_size")) - maxSizeDrops; drops != 2 {
+		t.Error("2 traps should be dropped beyond the maximum size, got", drops)
+	}
+
+	trapSender.configuration.SpoolMaxSize = 0
+	trapSender.configuration.SpoolMaxAge = time.Nanosecond
+	if err := trapSender.spoolTraps(0, []snmpgo.VarBinds{trap}); err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+	files, err := listSpooledTraps(trapSender.spoolDirectory(0))
+	if err != nil || len(files) != 1 {
+		t.Fatal("1 trap should be spooled, got", files, err)
+	}
+	spooledTrap, _, err := readSpooledTrap(files[0].path)
+	if err != nil || spooledTrap.String() != trap.String() {
+		t.Error("spooled trap", spooledTrap, "differs from", trap, err)
+	}
+
+	trapSender.replaySpooledTraps(0)
+	if drops := testutil.ToFloat64(telemetry.SNMPSpoolDroppedTotal.WithLabelValues(address, "max_age")) - maxAgeDrops; drops != 1 {
+		t.Error("1 trap should be dropped beyond the maximum age, got", drops)
+	}
+	if length := testutil.ToFloat64(telemetry.SNMPSpoolLength.WithLabelValues(address)); length != 0 {
+		t.Error("the spool should be empty, got", length)
+	}
+}
+
+func TestFailedReplaysCountedApart(t *testing.T) {
+	port, server, _, err := testutils.LaunchTrapReceiver()
 	if err != nil {
-		t.Error("An unexpected error occurred:", err)
+		t.Fatal("Error while opening server:", err)
+	}
+	server.Close()
+
+	address := fmt.Sprintf("127.0.0.1:%d", *port)
+	trapSender := New(Configuration{
+		SNMPDestinations: []Destination{
+			{Name: "manager", Address: address, Retries: 0, Version: "V2c", Timeout: 100 * time.Millisecond, Inform: true, Community: "public"},
+		},
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+		UserObjects:         make([]UserObject, 0),
+		SpoolDirectory:      t.TempDir(),
+		SpoolReplayInterval: time.Hour,
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	defer trapSender.Stop()
+
+	failures := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure"))
+	retries := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "retry"))
+
+	if err := trapSender.SendAlertTraps(readBucketFile(t, "test_mixed_bucket.json")); err != nil {
+		t.Fatal("spooled traps should not be reported as errors:", err)
+	}
+	trapSender.replaySpooledTraps(0)
+	trapSender.replaySpooledTraps(0)
+
+	// 2 traps failed once, and the first one was replayed twice, the second one waiting for it
+	if count := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure")) - failures; count != 2 {
+		t.Error("2 failed traps expected, got", count)
+	}
+	if count := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "retry")) - retries; count != 2 {
+		t.Error("2 failed replays expected, got", count)
+	}
+}
+
+func TestQueuedHeartbeat(t *testing.T) {
+	port, server, channel, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	defer server.Close()
+
+	expectHeartbeatTraps(t, "test_heartbeat_traps.json", queuedInformConfiguration(fmt.Sprintf("127.0.0.1:%d", *port)), channel)
+}
+
+func TestHeartbeatInFullQueue(t *testing.T) {
+	// a destination never acknowledging the informs keeps the only worker busy
+	connection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error while opening connection:", err)
+	}
+	defer connection.Close()
+
+	address := connection.LocalAddr().String()
+	dropped := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "dropped"))
+
+	trapSender := New(queuedInformConfiguration(address), slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	defer trapSender.Stop()
+
+	if err := trapSender.SendHeartbeat(testHeartbeat); err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+	// let the worker pick the first heartbeat up, the second one then fills the queue
+	time.Sleep(100 * time.Millisecond)
+	if err := trapSender.SendHeartbeat(testHeartbeat); err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+
+	if err := trapSender.SendHeartbeat(testHeartbeat); err == nil || !strings.Contains(err.Error(), "queue full for destinations: manager") {
+		t.Errorf("the heartbeat should be dropped by the full queue, got %v", err)
+	}
+	if count := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "dropped")) - dropped; count != 1 {
+		t.Errorf("1 dropped heartbeat trap expected, got %v", count)
+	}
+}
+
+func TestHeartbeatNotSpooled(t *testing.T) {
+	port, server, _, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	server.Close()
+
+	address := fmt.Sprintf("127.0.0.1:%d", *port)
+	for _, queueSize := range []int{0, 1} {
+		configuration := queuedInformConfiguration(address)
+		configuration.QueueSize = queueSize
+		configuration.SpoolDirectory = t.TempDir()
+		configuration.SpoolReplayInterval = time.Hour
+		trapSender := New(configuration, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+		failures := testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure"))
+		err := trapSender.SendHeartbeat(testHeartbeat)
+		if queueSize == 0 && err == nil {
+			t.Error("the failed heartbeat should be reported when not queued")
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for testutil.ToFloat64(telemetry.SNMPTrapTotal.WithLabelValues(address, "failure")) == failures {
+			if time.Now().After(deadline) {
+				t.Fatal("the heartbeat trap should fail, queue size", queueSize)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		trapSender.Stop()
+
+		files, err := listSpooledTraps(trapSender.spoolDirectory(0))
+		if err != nil || len(files) != 0 {
+			t.Error("the failed heartbeat trap should not be spooled, queue size", queueSize, files, err)
+		}
+	}
+}
+
+func TestV2TrapWithInvalidDescriptionTemplate(t *testing.T) {
+	port, server, _, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
 	}
+	defer server.Close()
+
+	expectErrorOnSending(t,
+		"test_mixed_bucket.json",
+		Configuration{
+			SNMPDestinations: []Destination{
+				{
+					Address:                fmt.Sprintf("127.0.0.1:%d", *port),
+					Retries:                1,
+					Version:                "V2c",
+					Timeout:                5 * time.Second,
+					Community:              "public",
+					AuthenticationEnabled:  false,
+					AuthenticationProtocol: "",
+					AuthenticationUsername: "",
+					AuthenticationPassword: "",
+					PrivateEnabled:         false,
+					PrivateProtocol:        "",
+					PrivatePassword:        "",
+					SecurityEngineID:       "",
+					ContextEngineID:        "",
+					ContextName:            "",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("invalidDescriptionTemplate").Parse(invalidDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
+		})
+}
 
+func TestV3TrapWithAuthenticationError(t *testing.T) {
+	port, server, _, err := testutils.LaunchTrapReceiver()
+	if err != nil {
+		t.Fatal("Error while opening server:", err)
+	}
+	defer server.Close()
+
+	expectErrorOnSending(t,
+		"test_mixed_bucket.json",
+		Configuration{
+			SNMPDestinations: []Destination{
+				{
+					Address:                fmt.Sprintf("127.0.0.1:%d", *port),
+					Retries:                1,
+					Version:                "V3",
+					Timeout:                5 * time.Second,
+					Community:              "",
+					AuthenticationEnabled:  true,
+					AuthenticationProtocol: "SHA",
+					AuthenticationUsername: "v3_username",
+					AuthenticationPassword: "v3_password",
+					PrivateEnabled:         true,
+					PrivateProtocol:        "AES",
+					PrivatePassword:        "v3_private_secret",
+					SecurityEngineID:       "",
+					ContextEngineID:        "",
+					ContextName:            "",
+				},
+			},
+			DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+			UserObjects:         make([]UserObject, 0),
+		})
+}
+
+func expectErrorOnSending(t *testing.T, bucketFileName string, configuration Configuration) {
+	bucketData := readBucketFile(t, bucketFileName)
+
+	trapSender := New(configuration, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	err := trapSender.SendAlertTraps(bucketData)
 	if err == nil {
-		receivedTraps := testutils.ReadTraps(channel)
+		t.Error("An error was expected")
+	}
+}
+
+func expectTraps(t *testing.T, bucketFileName string, trapFileName string, configuration Configuration, channels ...chan *snmpgo.TrapRequest) {
+	if sendTraps(t, bucketFileName, configuration) {
+		for _, channel := range channels {
+			expectReceivedTraps(t, trapFileName, channel)
+		}
+	}
+}
 
-		log.Print("Traps received:", receivedTraps)
+func sendTraps(t *testing.T, bucketFileName string, configuration Configuration) bool {
+	bucketData := readBucketFile(t, bucketFileName)
 
-		if len(receivedTraps) != 2 {
-			t.Error("2 traps expected, but received", receivedTraps)
+	trapSender := New(configuration, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	err := trapSender.SendAlertTraps(bucketData)
+	if err != nil {
+		t.Error("An unexpected error occurred:", err)
+	}
+	return err == nil
+}
+
+// queuedInformConfiguration sends informs to a destination through a queue of a single trap, with a single worker
+func queuedInformConfiguration(address string) Configuration {
+	return Configuration{
+		SNMPDestinations: []Destination{
+			{
+				Name:      "manager",
+				Address:   address,
+				Retries:   0,
+				Version:   "V2c",
+				Timeout:   500 * time.Millisecond,
+				Inform:    true,
+				Community: "public",
+			},
+		},
+		DescriptionTemplate: *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate)),
+		UserObjects:         make([]UserObject, 0),
+		QueueSize:           1,
+		QueueWorkers:        1,
+	}
+}
+
+func expectHeartbeatTraps(t *testing.T, trapFileName string, configuration Configuration, channel chan *snmpgo.TrapRequest) {
+	trapSender := New(configuration, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	defer trapSender.Stop()
+
+	if err := trapSender.SendHeartbeat(testHeartbeat); err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+	expectReceivedTraps(t, trapFileName, channel)
+}
+
+func expectReceivedTraps(t *testing.T, trapFileName string, channel chan *snmpgo.TrapRequest) {
+	receivedTraps := testutils.ReadTraps(channel)
+
+	log.Print("Traps received:", receivedTraps)
+
+	expectedTrapsData := readTrapsFile(t, trapFileName)
+
+	if len(receivedTraps) != len(expectedTrapsData) {
+		t.Error(len(expectedTrapsData), "traps expected, but received", receivedTraps)
+	}
+
+	for _, expectedTrap := range expectedTrapsData {
+		if !testutils.FindTrap(receivedTraps, expectedTrap) {
+			t.Fatal("Expected trap not found:", expectedTrap)
 		}
+	}
+}
 
-		expectedTrapsByteData, err := os.ReadFile(trapFileName)
-		if err != nil {
-			t.Fatal("Error while reading traps file:", err)
+func findRenderedTrap(traps []RenderedTrap, destination string, variables map[string]string) bool {
+	for _, trap := range traps {
+		if !slices.Contains(trap.Destinations, destination) {
+			continue
 		}
-		expectedTrapsReader := bytes.NewReader(expectedTrapsByteData)
-		expectedTrapsData := []map[string]string{}
-		err = json.NewDecoder(expectedTrapsReader).Decode(&expectedTrapsData)
-		if err != nil {
-			t.Fatal("Error while parsing traps file:", err)
+		values := map[string]string{}
+		for _, varBind := range trap.VarBinds {
+			values[varBind.OID] = varBind.Value
+		}
+		matches := true
+		for oid, value := range variables {
+			matches = matches && values[oid] == value
 		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+func renderSizeLimitedTraps(t *testing.T, configuration Configuration) []RenderedTrap {
+	configuration.SNMPDestinations = []Destination{{Name: "destination", Address: "127.0.0.1:162", Version: "V2c", Community: "public"}}
+	configuration.DescriptionTemplate = *template.Must(template.New("dummyDescriptionTemplate").Parse(dummyDescriptionTemplate))
 
-		for _, expectedTrap := range expectedTrapsData {
-			if !testutils.FindTrap(receivedTraps, expectedTrap) {
-				t.Fatal("Expected trap not found:", expectedTrap)
+	traps, err := New(configuration, slog.New(slog.NewTextHandler(os.Stdout, nil))).RenderTraps(readBucketFile(t, "test_mixed_bucket.json"))
+	if err != nil {
+		t.Fatal("An unexpected error occurred:", err)
+	}
+	return traps
+}
+
+func findRenderedVarBind(traps []RenderedTrap, groupID string, oid string) string {
+	for _, trap := range traps {
+		for _, varBind := range trap.VarBinds {
+			if trap.GroupID == groupID && varBind.OID == oid {
+				return varBind.Value
 			}
 		}
 	}
+	return ""
+}
+
+func readTrapsFile(t *testing.T, trapFileName string) []map[string]string {
+	expectedTrapsByteData, err := os.ReadFile(trapFileName)
+	if err != nil {
+		t.Fatal("Error while reading traps file:", err)
+	}
+	expectedTrapsReader := bytes.NewReader(expectedTrapsByteData)
+	expectedTrapsData := []map[string]string{}
+	err = json.NewDecoder(expectedTrapsReader).Decode(&expectedTrapsData)
+	if err != nil {
+		t.Fatal("Error while parsing traps file:", err)
+	}
+	return expectedTrapsData
+}
+
+func readBucketFile(t *testing.T, bucketFileName string) types.AlertBucket {
+	bucketByteData, err := os.ReadFile(bucketFileName)
+	if err != nil {
+		t.Fatal("Error while reading bucket file:", err)
+	}
+	bucketReader := bytes.NewReader(bucketByteData)
+	bucketData := types.AlertBucket{}
+	err = json.NewDecoder(bucketReader).Decode(&bucketData)
+	if err != nil {
+		t.Fatal("Error while parsing bucket file:", err)
+	}
+	return bucketData
 }
@@ -0,0 +1,198 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trapsender
+
+import (
+	"encoding/asn1"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/k-sone/snmpgo"
+
+	"github.com/maxwo/snmp_notifier/telemetry"
+)
+
+const (
+	snmpV1Version            = 0
+	snmpV1TrapPduTag         = 4
+	snmpV1EnterpriseSpecific = 6
+)
+
+var (
+	oidSnmpTraps          = snmpgo.MustNewOid("1.3.6.1.6.3.1.1.5")
+	oidSnmpTrapEnterprise = snmpgo.MustNewOid("1.3.6.1.6.3.1.1.4.3.0")
+)
+
+// v1Trap describes an SNMPv1 Trap-PDU
+type v1Trap struct {
+	enterprise   *snmpgo.Oid
+	agentAddress net.IP
+	genericTrap  int
+	specificTrap int
+	timeStamp    uint32
+	varBinds     snmpgo.VarBinds
+}
+
+// newV1Trap converts the variable bindings of an SNMPv2 trap into an SNMPv1 trap, according to RFC 3584 section 3.2
+func newV1Trap(varBinds snmpgo.VarBinds, agentAddress net.IP) v1Trap {
+	var (
+		trapOID    *snmpgo.Oid
+		enterprise *snmpgo.Oid
+	)
+
+	trap := v1Trap{
+		agentAddress: agentAddress,
+		varBinds:     snmpgo.VarBinds{},
+	}
+
+	for _, varBind := range varBinds {
+		switch {
+		case varBind.Oid.Equal(snmpgo.OidSysUpTime):
+			if timeTicks, ok := varBind.Variable.(*snmpgo.TimeTicks); ok {
+				trap.timeStamp = timeTicks.Value
+			}
+		case varBind.Oid.Equal(snmpgo.OidSnmpTrap):
+			trapOID, _ = varBind.Variable.(*snmpgo.Oid)
+		case varBind.Oid.Equal(oidSnmpTrapEnterprise):
+			enterprise, _ = varBind.Variable.(*snmpgo.Oid)
+		default:
+			// Counter64 values can not be represented in SNMPv1
+			if _, isCounter64 := varBind.Variable.(*snmpgo.Counter64); !isCounter64 {
+				trap.varBinds = append(trap.varBinds, varBind)
+			}
+		}
+	}
+
+	subIDs := trapOID.Value
+	lastSubID := subIDs[len(subIDs)-1]
+
+	if trapOID.Contains(oidSnmpTraps) && len(subIDs) == len(oidSnmpTraps.Value)+1 && lastSubID >= 1 && lastSubID <= 6 {
+		// generic traps, from coldStart to egpNeighborLoss
+		trap.genericTrap = lastSubID - 1
+		trap.specificTrap = 0
+		trap.enterprise = oidSnmpTraps
+		if enterprise != nil {
+			trap.enterprise = enterprise
+		}
+		return trap
+	}
+
+	trap.genericTrap = snmpV1EnterpriseSpecific
+	trap.specificTrap = lastSubID
+	if len(subIDs) > 2 && subIDs[len(subIDs)-2] == 0 {
+		trap.enterprise = &snmpgo.Oid{Value: subIDs[:len(subIDs)-2]}
+	} else {
+		trap.enterprise = &snmpgo.Oid{Value: subIDs[:len(subIDs)-1]}
+	}
+	return trap
+}
+
+// marshal encodes the trap as an SNMPv1 message with the given community
+func (trap v1Trap) marshal(community string) ([]byte, error) {
+	agentAddress := trap.agentAddress.To4()
+	if agentAddress == nil {
+		return nil, errors.New("SNMPv1 agent address must be an IPv4 address")
+	}
+
+	pdu := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: snmpV1TrapPduTag, IsCompound: true}
+
+	fields := []interface{ Marshal() ([]byte, error) }{
+		trap.enterprise,
+		snmpgo.NewIpaddress(agentAddress[0], agentAddress[1], agentAddress[2], agentAddress[3]),
+		snmpgo.NewInteger(int32(trap.genericTrap)),
+		snmpgo.NewInteger(int32(trap.specificTrap)),
+		snmpgo.NewTimeTicks(trap.timeStamp),
+	}
+	for _, field := range fields {
+		buffer, err := field.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		pdu.Bytes = append(pdu.Bytes, buffer...)
+	}
+
+	varBinds := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true}
+	for _, varBind := range trap.varBinds {
+		buffer, err := varBind.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		varBinds.Bytes = append(varBinds.Bytes, buffer...)
+	}
+	buffer, err := asn1.Marshal(varBinds)
+	if err != nil {
+		return nil, err
+	}
+	pdu.Bytes = append(pdu.Bytes, buffer...)
+
+	message := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true}
+	for _, field := range []interface{}{snmpV1Version, []byte(community), pdu} {
+		buffer, err := asn1.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		message.Bytes = append(message.Bytes, buffer...)
+	}
+
+	return asn1.Marshal(message)
+}
+
+func (trapSender TrapSender) sendV1Traps(destination Destination, traps []snmpgo.VarBinds) ([]snmpgo.VarBinds, error) {
+	distinationForMetrics := destination.Address
+
+	connection, err := net.DialTimeout("udp", destination.Address, destination.Timeout)
+	if err != nil {
+		trapSender.logger.Error("error while opening SNMP connection", "err", err.Error())
+		return traps, err
+	}
+
+	defer func() {
+		connection.Close()
+	}()
+
+	agentAddress := net.ParseIP(destination.AgentAddress)
+
+	failedTraps := []snmpgo.VarBinds{}
+	for _, trap := range traps {
+		packet, err := newV1Trap(trap, agentAddress).marshal(destination.Community)
+		if err == nil {
+			err = writeWithRetries(connection, packet, destination.Retries, destination.Timeout)
+		}
+		if err != nil {
+			trapSender.logger.Error("error while sending trap", "destination", distinationForMetrics, "err", err.Error())
+			failedTraps = append(failedTraps, trap)
+			continue
+		}
+		telemetry.SNMPTrapTotal.WithLabelValues(distinationForMetrics, "success").Inc()
+	}
+
+	if len(failedTraps) > 0 {
+		return failedTraps, errors.New("error while sending one or more traps")
+	}
+	return nil, nil
+}
+
+// writeWithRetries writes a packet, retrying on failure as SNMPv1 traps are not acknowledged
+func writeWithRetries(connection net.Conn, packet []byte, retries uint, timeout time.Duration) (err error) {
+	for attempt := uint(0); attempt <= retries; attempt++ {
+		if err = connection.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+			return err
+		}
+		if _, err = connection.Write(packet); err == nil {
+			return nil
+		}
+	}
+	return err
+}
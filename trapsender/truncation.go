@@ -0,0 +1,100 @@
+// Copyright 2026 Maxime Wojtczak
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trapsender
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/k-sone/snmpgo"
+
+	"github.com/maxwo/snmp_notifier/commons"
+	"github.com/maxwo/snmp_notifier/types"
+)
+
+const truncationSuffix = "..."
+
+// truncateString shortens a text to a maximum number of bytes, suffix included, without splitting a character
+func truncateString(text string, size int, suffix string) string {
+	if len(text) <= size {
+		return text
+	}
+	if size <= len(suffix) {
+		return commons.Truncate(size, suffix)
+	}
+	return commons.Truncate(size-len(suffix), text) + suffix
+}
+
+// moreAlertsSuffix tells how many alerts were left out of a truncated description
+func moreAlertsSuffix(moreAlerts int) string {
+	if moreAlerts == 0 {
+		return truncationSuffix
+	}
+	if moreAlerts == 1 {
+		return truncationSuffix + " (1 more alert)"
+	}
+	return fmt.Sprintf("%s (%d more alerts)", truncationSuffix, moreAlerts)
+}
+
+// truncateDescription renders the description of the first alerts of a group only, as many as fit in the given size,
+// followed by the number of alerts left out. When a single alert does not fit, its description is cut.
+func (trapSender TrapSender) truncateDescription(alertGroup types.AlertGroup, size int) (string, error) {
+	alerts := alertGroup.Alerts
+	render := func(count int) (string, error) {
+		alertGroup.Alerts = alerts[:count]
+		description, err := commons.FillTemplate(alertGroup, trapSender.configuration.DescriptionTemplate)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(*description) + moreAlertsSuffix(len(alerts)-count), nil
+	}
+
+	var err error
+	// the largest number of alerts whose description fits, at least one, as the description grows with the alerts
+	count := sort.Search(len(alerts)-1, func(index int) bool {
+		description, renderErr := render(index + 2)
+		if renderErr != nil {
+			err = renderErr
+			return true
+		}
+		return len(description) > size
+	}) + 1
+	if err != nil {
+		return "", err
+	}
+	count = min(count, len(alerts))
+
+	description, err := render(count)
+	if err != nil {
+		return "", err
+	}
+	if len(description) <= size {
+		return description, nil
+	}
+	suffix := moreAlertsSuffix(len(alerts) - count)
+	if size <= len(suffix) {
+		return commons.Truncate(size, suffix), nil
+	}
+	return commons.Truncate(size-len(suffix), strings.TrimSuffix(description, suffix)) + suffix, nil
+}
+
+// pduSize returns the size of the encoded PDU of a trap, without the header of the SNMP message
+func pduSize(varBinds snmpgo.VarBinds) (int, error) {
+	pdu, err := snmpgo.NewPduWithVarBinds(snmpgo.V2c, snmpgo.SNMPTrapV2, varBinds).Marshal()
+	if err != nil {
+		return 0, err
+	}
+	return len(pdu), nil
+}
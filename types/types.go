@@ -23,26 +23,37 @@ type Alert = alertmanagertemplate.Alert
 // Alerts is a set of alerts received from the Alertmanager
 type Alerts = alertmanagertemplate.Alerts
 
-// AlertsData is the alerts object received from the Alertmanager
-type AlertsData = alertmanagertemplate.Data
+// AlertsData is the alerts object received from the Alertmanager, with the key identifying its alert group
+type AlertsData struct {
+	alertmanagertemplate.Data
+	GroupKey string `json:"groupKey"`
+}
 
 // AlertBucket mutualizes alerts by Trap IDs
 type AlertBucket struct {
 	AlertGroups map[string]*AlertGroup
 }
 
-// AlertGroup type, with OID and group ID
+// AlertGroup type, with OID and group ID. With the per-alert grouping, it holds a single alert identified by its fingerprint
 type AlertGroup struct {
 	TrapOID               string
 	GroupID               string
+	Fingerprint           string
+	GroupKey              string
+	Receiver              string
 	DefaultObjectsBaseOID string
 	UserObjectsBaseOID    string
 	GroupLabels           map[string]string
 	CommonLabels          map[string]string
 	CommonAnnotations     map[string]string
 	Severity              string
+	SeverityLevel         int
 	Alerts                []Alert
 	DeclaredAlerts        []Alert
+	// PartIndex and PartCount locate the alerts of a trap within their group, when groups are split into several
+	// traps, starting with 1. Both are 0 otherwise.
+	PartIndex int
+	PartCount int
 }
 
 // GetAlertGroupName allows to retrieve a group name from a given alert